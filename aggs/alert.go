@@ -0,0 +1,276 @@
+package aggs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertState 告警状态: 条件满足后先进入 pending, 连续满足 For 个窗口后 firing, 条件不再满足时 resolved
+type AlertState string
+
+const (
+	AlertPending  AlertState = "pending"
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+// alertOps 支持的比较运算符
+var alertOps = map[string]func(cmp int) bool{
+	">":  func(cmp int) bool { return cmp > 0 },
+	">=": func(cmp int) bool { return cmp >= 0 },
+	"<":  func(cmp int) bool { return cmp < 0 },
+	"<=": func(cmp int) bool { return cmp <= 0 },
+	"==": func(cmp int) bool { return cmp == 0 },
+	"!=": func(cmp int) bool { return cmp != 0 },
+}
+
+// AlertRule 阈值告警规则,可以在代码中构建也可以通过 LoadAlertRules 从YAML读取
+// 例如 error_rate > 0.05 连续3个窗口, 按service分组:
+//
+//	AlertRule{Name: "high_error_rate", Expr: "error_rate > 0.05", For: 3, GroupBy: []string{"service"}}
+type AlertRule struct {
+	Name string `yaml:"name"`
+	// Expr 形如 "field op threshold" 的表达式,设置后覆盖 Field/Op/Threshold
+	Expr      string   `yaml:"expr"`
+	Field     string   `yaml:"field"`
+	Op        string   `yaml:"op"`
+	Threshold float64  `yaml:"threshold"`
+	For       int      `yaml:"for"` // 连续满足条件的窗口数,小于1时视为1
+	GroupBy   []string `yaml:"group_by"`
+	Message   string   `yaml:"message"`
+	// RepeatInterval firing 状态下重复通知的间隔,为0时只在状态变化时通知
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+}
+
+// Alert 发送给 Notifier 的告警
+type Alert struct {
+	Rule        string          `json:"rule"`
+	State       AlertState      `json:"state"`
+	Group       Row             `json:"group"`
+	Value       decimal.Decimal `json:"value"`
+	Threshold   float64         `json:"threshold"`
+	Message     string          `json:"message"`
+	Fingerprint string          `json:"fingerprint"` // 规则+分组,用于去重
+	StartsAt    time.Time       `json:"starts_at"`
+	EndsAt      time.Time       `json:"ends_at,omitempty"`
+}
+
+// Notifier 告警通知的扩展点
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// Alerter 对每个窗口的聚合结果评估告警规则,跟踪告警状态并在状态变化时通知,并发安全
+type Alerter struct {
+	mu        sync.Mutex
+	rules     []AlertRule
+	notifiers []Notifier
+	states    map[string]*alertState
+	now       func() time.Time
+}
+
+type alertState struct {
+	alert    Alert
+	hits     int
+	notified time.Time
+}
+
+// LoadAlertRules 从YAML文件读取规则, 文件格式为 rules: [AlertRule...]
+func LoadAlertRules(filePath string) ([]AlertRule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var conf struct {
+		Rules []AlertRule `yaml:"rules"`
+	}
+	if err = yaml.Unmarshal(data, &conf); err != nil {
+		return nil, err
+	}
+	return conf.Rules, nil
+}
+
+func NewAlerter(rules []AlertRule, notifiers ...Notifier) (*Alerter, error) {
+	parsed := make([]AlertRule, 0, len(rules))
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if err := rule.parse(); err != nil {
+			return nil, err
+		}
+		// 告警状态按规则名+分组记录,重名的规则会互相覆盖和恢复对方的告警
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("aggs: duplicate alert rule name %q", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		parsed = append(parsed, rule)
+	}
+	return &Alerter{
+		rules:     parsed,
+		notifiers: notifiers,
+		states:    make(map[string]*alertState),
+		now:       time.Now,
+	}, nil
+}
+
+// parse 解析Expr并校验规则
+func (r *AlertRule) parse() error {
+	if r.Name == "" {
+		return errors.New("aggs: alert rule name is empty")
+	}
+	if r.Expr != "" {
+		parts := strings.Fields(r.Expr)
+		if len(parts) != 3 {
+			return fmt.Errorf("aggs: alert rule %s: invalid expr %q", r.Name, r.Expr)
+		}
+		threshold, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return fmt.Errorf("aggs: alert rule %s: invalid threshold %q", r.Name, parts[2])
+		}
+		r.Field, r.Op, r.Threshold = parts[0], parts[1], threshold
+	}
+	if r.Field == "" {
+		return fmt.Errorf("aggs: alert rule %s: field is empty", r.Name)
+	}
+	if _, ok := alertOps[r.Op]; !ok {
+		return fmt.Errorf("aggs: alert rule %s: unsupported op %q", r.Name, r.Op)
+	}
+	if r.For < 1 {
+		r.For = 1
+	}
+	return nil
+}
+
+// match 返回row是否满足条件以及字段值
+func (r *AlertRule) match(row Row) (decimal.Decimal, bool) {
+	v, ok := ToDecimal(row[r.Field])
+	if !ok {
+		return v, false
+	}
+	return v, alertOps[r.Op](v.Cmp(decimal.NewFromFloat(r.Threshold)))
+}
+
+// Evaluate 将rows作为一个窗口的结果评估所有规则,返回需要通知的告警
+// 本次没有出现或不满足条件的分组视为恢复; 通知失败时返回所有 Notifier 的错误
+func (a *Alerter) Evaluate(ctx context.Context, rows []Row) ([]Alert, error) {
+	a.mu.Lock()
+	now := a.now()
+	var notify []Alert
+	for i := range a.rules {
+		notify = append(notify, a.evaluateRule(&a.rules[i], rows, now)...)
+	}
+	a.mu.Unlock()
+
+	if len(notify) == 0 {
+		return nil, nil
+	}
+	var errs []error
+	for _, n := range a.notifiers {
+		if err := n.Notify(ctx, notify); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return notify, errors.Join(errs...)
+}
+
+// evaluateRule 需持有mu
+func (a *Alerter) evaluateRule(rule *AlertRule, rows []Row, now time.Time) []Alert {
+	var notify []Alert
+	seen := make(map[string]struct{})
+	for _, row := range rows {
+		value, ok := rule.match(row)
+		if !ok {
+			continue
+		}
+		fingerprint := rule.fingerprint(row)
+		if _, dup := seen[fingerprint]; dup {
+			continue
+		}
+		seen[fingerprint] = struct{}{}
+		st, exists := a.states[fingerprint]
+		if !exists {
+			st = &alertState{alert: Alert{
+				Rule:        rule.Name,
+				State:       AlertPending,
+				Group:       make(Row, len(rule.GroupBy)),
+				Threshold:   rule.Threshold,
+				Fingerprint: fingerprint,
+				StartsAt:    now,
+			}}
+			for _, field := range rule.GroupBy {
+				st.alert.Group[field] = row[field]
+			}
+			a.states[fingerprint] = st
+		}
+		st.hits++
+		st.alert.Value = value
+		st.alert.Message = rule.message(value)
+		if st.hits < rule.For {
+			continue
+		}
+		repeat := rule.RepeatInterval > 0 && now.Sub(st.notified) >= rule.RepeatInterval
+		if st.alert.State != AlertFiring || repeat {
+			st.alert.State = AlertFiring
+			st.notified = now
+			notify = append(notify, st.alert)
+		}
+	}
+	// 本轮不再满足条件的告警: firing 的恢复并通知, pending 的直接清除
+	var fingerprints []string
+	for fingerprint, st := range a.states {
+		if st.alert.Rule != rule.Name {
+			continue
+		}
+		if _, ok := seen[fingerprint]; !ok {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	sort.Strings(fingerprints)
+	for _, fingerprint := range fingerprints {
+		st := a.states[fingerprint]
+		delete(a.states, fingerprint)
+		if st.alert.State == AlertFiring {
+			st.alert.State = AlertResolved
+			st.alert.EndsAt = now
+			notify = append(notify, st.alert)
+		}
+	}
+	return notify
+}
+
+// fingerprint 规则名+分组值, 形如 high_error_rate{service=api}
+func (r *AlertRule) fingerprint(row Row) string {
+	parts := make([]string, len(r.GroupBy))
+	for i, field := range r.GroupBy {
+		parts[i] = fmt.Sprintf("%s=%v", field, deref(row[field]))
+	}
+	return r.Name + "{" + strings.Join(parts, ",") + "}"
+}
+
+func (r *AlertRule) message(value decimal.Decimal) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return fmt.Sprintf("%s: %s=%s %s %s", r.Name, r.Field, value.String(), r.Op,
+		strconv.FormatFloat(r.Threshold, 'f', -1, 64))
+}
+
+// Active 返回当前 pending 和 firing 的告警
+func (a *Alerter) Active() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	res := make([]Alert, 0, len(a.states))
+	for _, st := range a.states {
+		res = append(res, st.alert)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Fingerprint < res[j].Fingerprint })
+	return res
+}
@@ -0,0 +1,115 @@
+package aggs
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// CompareOption 同环比计算的配置,零值字段使用默认值
+type CompareOption struct {
+	// Placeholder 基期缺失或为0时,无法计算的字段填充的值,默认 "-"
+	Placeholder interface{}
+	// Places 增长率和比值保留的小数位数,默认4; 为负数时不做舍入
+	Places      int32
+	PrevSuffix  string // 基期值字段后缀,默认 "_prev"
+	DeltaSuffix string // 差值字段后缀,默认 "_delta"
+	RateSuffix  string // 增长率字段后缀,默认 "_rate",  (本期-基期)/|基期|
+	RatioSuffix string // 比值字段后缀,默认 "_ratio", 本期/基期
+	// KeepPrevious 基期存在而本期不存在的行也输出,本期度量按0计算
+	KeepPrevious bool
+}
+
+func (o CompareOption) withDefault() CompareOption {
+	if o.Placeholder == nil {
+		o.Placeholder = "-"
+	}
+	if o.Places == 0 {
+		o.Places = 4
+	}
+	if o.PrevSuffix == "" {
+		o.PrevSuffix = "_prev"
+	}
+	if o.DeltaSuffix == "" {
+		o.DeltaSuffix = "_delta"
+	}
+	if o.RateSuffix == "" {
+		o.RateSuffix = "_rate"
+	}
+	if o.RatioSuffix == "" {
+		o.RatioSuffix = "_ratio"
+	}
+	return o
+}
+
+// Compare 按joinKeys关联本期和基期数据,为每个度量输出基期值,差值,增长率和比值(decimal.Decimal)
+// 输出的行是本期行的拷贝,顺序与current一致; 基期缺失时四个字段均为占位值,基期为0时增长率和比值为占位值
+func Compare(current, previous []Row, joinKeys []string, measures []string, opts ...CompareOption) []Row {
+	var opt CompareOption
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	opt = opt.withDefault()
+
+	prevIndex := make(map[string]Row, len(previous))
+	for _, row := range previous {
+		prevIndex[groupKey(row, joinKeys)] = row
+	}
+	matched := make(map[string]struct{}, len(current))
+	res := make([]Row, 0, len(current))
+	for _, cur := range current {
+		key := groupKey(cur, joinKeys)
+		prev, ok := prevIndex[key]
+		if ok {
+			matched[key] = struct{}{}
+		}
+		res = append(res, opt.compareRow(cur, prev, measures))
+	}
+	if opt.KeepPrevious {
+		for _, prev := range previous {
+			if _, ok := matched[groupKey(prev, joinKeys)]; ok {
+				continue
+			}
+			cur := make(Row, len(joinKeys))
+			for _, field := range joinKeys {
+				cur[field] = prev[field]
+			}
+			res = append(res, opt.compareRow(cur, prev, measures))
+		}
+	}
+	return res
+}
+
+func (o CompareOption) compareRow(cur, prev Row, measures []string) Row {
+	row := make(Row, len(cur)+len(measures)*4)
+	for k, v := range cur {
+		row[k] = v
+	}
+	for _, field := range measures {
+		curVal, _ := ToDecimal(cur[field])
+		prevVal, ok := ToDecimal(prev[field])
+		if !ok {
+			row[field+o.PrevSuffix] = o.Placeholder
+			row[field+o.DeltaSuffix] = o.Placeholder
+			row[field+o.RateSuffix] = o.Placeholder
+			row[field+o.RatioSuffix] = o.Placeholder
+			continue
+		}
+		delta := curVal.Sub(prevVal)
+		row[field+o.PrevSuffix] = prevVal
+		row[field+o.DeltaSuffix] = delta
+		if prevVal.IsZero() {
+			row[field+o.RateSuffix] = o.Placeholder
+			row[field+o.RatioSuffix] = o.Placeholder
+			continue
+		}
+		row[field+o.RateSuffix] = o.round(delta.Div(prevVal.Abs()))
+		row[field+o.RatioSuffix] = o.round(curVal.Div(prevVal))
+	}
+	return row
+}
+
+func (o CompareOption) round(d decimal.Decimal) decimal.Decimal {
+	if o.Places < 0 {
+		return d
+	}
+	return d.Round(o.Places)
+}
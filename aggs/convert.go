@@ -0,0 +1,134 @@
+package aggs
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts ToTime 解析字符串时依次尝试的格式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.DateTime,
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// ToDecimal 将查询结果中的数值统一转换为decimal.Decimal
+// 兼容各驱动返回的不同类型: 整数,浮点, []byte, string, decimal.Decimal 及其指针; NaN 和 ±Inf 不能转换
+func ToDecimal(value interface{}) (decimal.Decimal, bool) {
+	value = deref(value)
+	switch v := value.(type) {
+	case nil:
+		return decimal.Zero, false
+	case decimal.Decimal:
+		return v, true
+	case int:
+		return decimal.NewFromInt(int64(v)), true
+	case int8:
+		return decimal.NewFromInt(int64(v)), true
+	case int16:
+		return decimal.NewFromInt(int64(v)), true
+	case int32:
+		return decimal.NewFromInt(int64(v)), true
+	case int64:
+		return decimal.NewFromInt(v), true
+	case uint:
+		return decimal.NewFromUint64(uint64(v)), true
+	case uint8:
+		return decimal.NewFromUint64(uint64(v)), true
+	case uint16:
+		return decimal.NewFromUint64(uint64(v)), true
+	case uint32:
+		return decimal.NewFromUint64(uint64(v)), true
+	case uint64:
+		return decimal.NewFromUint64(v), true
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return decimal.Zero, false
+		}
+		return decimal.NewFromFloat32(v), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return decimal.Zero, false
+		}
+		return decimal.NewFromFloat(v), true
+	case []byte:
+		return parseDecimal(string(v))
+	case string:
+		return parseDecimal(v)
+	case bool:
+		if v {
+			return decimal.NewFromInt(1), true
+		}
+		return decimal.Zero, true
+	}
+	return decimal.Zero, false
+}
+
+// ToTime 将查询结果中的时间统一转换为time.Time
+// 支持 time.Time, string, []byte 以及 unix 秒级时间戳
+func ToTime(value interface{}) (time.Time, bool) {
+	value = deref(value)
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseTime(v)
+	case []byte:
+		return parseTime(string(v))
+	case int, int32, int64, uint, uint32, uint64:
+		d, _ := ToDecimal(v)
+		return time.Unix(d.IntPart(), 0), true
+	}
+	return time.Time{}, false
+}
+
+func parseDecimal(s string) (decimal.Decimal, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" {
+		return decimal.Zero, false
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	return d, true
+}
+
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), true
+	}
+	return time.Time{}, false
+}
+
+// deref 解开指针,nil指针返回nil
+func deref(value interface{}) interface{} {
+	for value != nil {
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Ptr {
+			return value
+		}
+		if v.IsNil() {
+			return nil
+		}
+		value = v.Elem().Interface()
+	}
+	return nil
+}
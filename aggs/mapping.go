@@ -0,0 +1,263 @@
+package aggs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+)
+
+// structField 结构体字段与Row字段的对应关系
+type structField struct {
+	name  string
+	index []int
+}
+
+// fieldName 获取字段在Row中的名称,优先使用 `aggs` 标签,其次使用 `json` 标签,没有标签则使用字段名本身; "-" 表示忽略
+func fieldName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"aggs", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return field.Name, true
+}
+
+// structFields 展开结构体的字段,没有标签名的匿名嵌入结构体会被拍平; 外层字段优先于嵌入字段
+func structFields(t reflect.Type) []structField {
+	var res []structField
+	seen := make(map[string]struct{})
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		var next []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			_, tagged := field.Tag.Lookup("aggs")
+			if !tagged {
+				_, tagged = field.Tag.Lookup("json")
+			}
+			if field.Anonymous && !tagged && ft.Kind() == reflect.Struct && ft != timeType && ft != decimalType {
+				// 未导出的嵌入指针无法分配内存,跳过
+				if field.IsExported() || field.Type.Kind() != reflect.Ptr {
+					next = append(next, field)
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			if _, dup := seen[name]; dup {
+				continue
+			}
+			seen[name] = struct{}{}
+			res = append(res, structField{name: name, index: append(append([]int{}, index...), i)})
+		}
+		for _, field := range next {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			walk(ft, append(append([]int{}, index...), field.Index...))
+		}
+	}
+	walk(t, nil)
+	return res
+}
+
+// FromStruct 将结构体(或其指针)转成Row, 字段名规则同 Scan; 非结构体返回nil
+func FromStruct(v interface{}) Row {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := structFields(rv.Type())
+	row := make(Row, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index, false)
+		if !ok {
+			continue
+		}
+		row[f.name] = fv.Interface()
+	}
+	return row
+}
+
+// Scan 将Row写入结构体指针dst, 按字段名规则匹配并做类型转换:
+// []byte/string -> 数值, string -> time.Time, 数值 -> decimal.Decimal 等; Row中不存在或为nil的字段保持不变
+// 所有无法转换的字段汇总成一个error返回
+func Scan(row Row, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("aggs: scan dst must be a non-nil pointer to a struct")
+	}
+	rv = rv.Elem()
+	var errs []error
+	for _, f := range structFields(rv.Type()) {
+		value, ok := row[f.name]
+		if !ok || deref(value) == nil {
+			continue
+		}
+		fv, _ := fieldByIndex(rv, f.index, true)
+		if err := setValue(fv, value); err != nil {
+			errs = append(errs, fmt.Errorf("aggs: scan field %s: %w", f.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fieldByIndex 按索引取字段, alloc 为true时为nil的嵌入指针分配内存
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// setValue 将value转换成fv的类型并赋值
+func setValue(fv reflect.Value, value interface{}) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setValue(fv.Elem(), value)
+	}
+	value = deref(value)
+	vt := reflect.TypeOf(value)
+	if vt.AssignableTo(fv.Type()) {
+		fv.Set(reflect.ValueOf(value))
+		return nil
+	}
+	switch fv.Type() {
+	case timeType:
+		t, ok := ToTime(value)
+		if !ok {
+			return fmt.Errorf("can't convert %T to time.Time", value)
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case decimalType:
+		d, ok := ToDecimal(value)
+		if !ok {
+			return fmt.Errorf("can't convert %T to decimal.Decimal", value)
+		}
+		fv.Set(reflect.ValueOf(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case []byte:
+			fv.SetString(string(v))
+		case decimal.Decimal:
+			fv.SetString(v.String())
+		case time.Time:
+			fv.SetString(v.Format(time.DateTime))
+		default:
+			fv.SetString(fmt.Sprintf("%v", v))
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d, ok := ToDecimal(value)
+		if !ok || !d.IsInteger() {
+			return fmt.Errorf("can't convert %v to %s", value, fv.Type())
+		}
+		if fv.OverflowInt(d.IntPart()) {
+			return fmt.Errorf("%v overflows %s", value, fv.Type())
+		}
+		fv.SetInt(d.IntPart())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		d, ok := ToDecimal(value)
+		if !ok || !d.IsInteger() || d.IsNegative() {
+			return fmt.Errorf("can't convert %v to %s", value, fv.Type())
+		}
+		u, err := strconv.ParseUint(d.String(), 10, 64)
+		if err != nil || fv.OverflowUint(u) {
+			return fmt.Errorf("%v overflows %s", value, fv.Type())
+		}
+		fv.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		d, ok := ToDecimal(value)
+		if !ok {
+			return fmt.Errorf("can't convert %v to %s", value, fv.Type())
+		}
+		f, _ := d.Float64()
+		fv.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		switch v := value.(type) {
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			fv.SetBool(b)
+		case []byte:
+			b, err := strconv.ParseBool(string(v))
+			if err != nil {
+				return err
+			}
+			fv.SetBool(b)
+		default:
+			d, ok := ToDecimal(value)
+			if !ok {
+				return fmt.Errorf("can't convert %T to bool", value)
+			}
+			fv.SetBool(!d.IsZero())
+		}
+		return nil
+	case reflect.Struct:
+		// 嵌套的结构体字段可以由 Row 或 map[string]interface{} 填充
+		switch v := value.(type) {
+		case Row:
+			return Scan(v, fv.Addr().Interface())
+		case map[string]interface{}:
+			return Scan(v, fv.Addr().Interface())
+		}
+	}
+	if vt.ConvertibleTo(fv.Type()) && vt.Kind() == fv.Kind() {
+		fv.Set(reflect.ValueOf(value).Convert(fv.Type()))
+		return nil
+	}
+	return fmt.Errorf("can't convert %T to %s", value, fv.Type())
+}
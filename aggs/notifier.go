@@ -0,0 +1,58 @@
+package aggs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wg00001/wgo-sdk/wg_log"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier 将告警以JSON数组POST到URL
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client // 为nil时使用10秒超时的默认client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("aggs: webhook %s returned %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// LogNotifier 使用 wg_log.Warring 记录告警的状态变化
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(_ context.Context, alerts []Alert) error {
+	for _, a := range alerts {
+		wg_log.Warring(fmt.Sprintf("[ALERT %s] %s %s", a.State, a.Fingerprint, a.Message))
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package aggs
+
+import (
+	"github.com/shopspring/decimal"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column 渲染的列: Field 为Row中的字段, Header 为表头,为空时使用Field
+type Column struct {
+	Field  string
+	Header string
+}
+
+// RenderOption 表格渲染配置,零值字段使用默认值
+type RenderOption struct {
+	// Columns 列的顺序和表头; 为空时使用所有行字段的并集,按字母升序
+	Columns []Column
+	// Format 单元格格式化,默认 formatCell: 与 NumToString 相同, decimal 与浮点数一样保留两位小数并加千分位
+	Format func(field string, value interface{}) string
+	// MarkerField/LevelField 与 RollupOption 一致,用于识别小计/合计行; 默认 "_rollup"/"_rollup_level", 不作为列输出
+	MarkerField string
+	LevelField  string
+}
+
+func (o RenderOption) withDefault(rows []Row) RenderOption {
+	if o.Format == nil {
+		o.Format = formatCell
+	}
+	if o.MarkerField == "" {
+		o.MarkerField = "_rollup"
+	}
+	if o.LevelField == "" {
+		o.LevelField = "_rollup_level"
+	}
+	if len(o.Columns) == 0 {
+		fields := make(map[string]struct{})
+		for _, row := range rows {
+			for k := range row {
+				if k != o.MarkerField && k != o.LevelField {
+					fields[k] = struct{}{}
+				}
+			}
+		}
+		for k := range fields {
+			o.Columns = append(o.Columns, Column{Field: k})
+		}
+		sort.Slice(o.Columns, func(i, j int) bool { return o.Columns[i].Field < o.Columns[j].Field })
+	}
+	for i := range o.Columns {
+		if o.Columns[i].Header == "" {
+			o.Columns[i].Header = o.Columns[i].Field
+		}
+	}
+	return o
+}
+
+// formatCell 默认的单元格格式化; 小计/合计为 decimal, 需要与明细行的浮点数格式一致
+func formatCell(_ string, value interface{}) string {
+	switch v := value.(type) {
+	case decimal.Decimal:
+		return formatDecimal(v)
+	case *decimal.Decimal:
+		if v == nil {
+			return ""
+		}
+		return formatDecimal(*v)
+	}
+	return NumToString(value)
+}
+
+func formatDecimal(d decimal.Decimal) string {
+	if d.IsNegative() {
+		return "-" + formatWithCommas(d.Neg().StringFixed(2))
+	}
+	return formatWithCommas(d.StringFixed(2))
+}
+
+// renderTable 格式化后的表格
+type renderTable struct {
+	headers []string
+	cells   [][]string
+	numeric []bool   // 列是否为数值列(所有非空单元格都是数字),数值列右对齐
+	markers []string // 每行的小计/合计标记
+}
+
+func buildTable(rows []Row, opts []RenderOption) renderTable {
+	var opt RenderOption
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	opt = opt.withDefault(rows)
+	t := renderTable{
+		headers: make([]string, len(opt.Columns)),
+		cells:   make([][]string, len(rows)),
+		numeric: make([]bool, len(opt.Columns)),
+		markers: make([]string, len(rows)),
+	}
+	for j, col := range opt.Columns {
+		t.headers[j] = col.Header
+		t.numeric[j] = true
+	}
+	filled := make([]bool, len(opt.Columns))
+	for i, row := range rows {
+		t.cells[i] = make([]string, len(opt.Columns))
+		t.markers[i] = NumToString(row[opt.MarkerField])
+		for j, col := range opt.Columns {
+			value, ok := row[col.Field]
+			if !ok || deref(value) == nil {
+				continue
+			}
+			t.cells[i][j] = opt.Format(col.Field, value)
+			if _, isNum := ToDecimal(t.cells[i][j]); isNum {
+				filled[j] = true
+			} else if t.cells[i][j] != "" {
+				t.numeric[j] = false
+			}
+		}
+	}
+	for j := range t.numeric {
+		t.numeric[j] = t.numeric[j] && filled[j]
+	}
+	return t
+}
+
+// ModuleRows 将 map[string]Module 转成按名称排序的 []Row, 名称写入keyField
+func ModuleRows(modules map[string]Module, keyField string) []Row {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]Row, 0, len(modules))
+	for _, name := range names {
+		row := Row{keyField: name}
+		for k, v := range modules[name] {
+			row[k] = v
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// RenderText 渲染成对齐的纯文本表格,适用于日志和聊天机器人消息; 按显示宽度对齐中文
+func RenderText(rows []Row, opts ...RenderOption) string {
+	t := buildTable(rows, opts)
+	widths := make([]int, len(t.headers))
+	for j, h := range t.headers {
+		widths[j] = displayWidth(h)
+	}
+	for _, cells := range t.cells {
+		for j, c := range cells {
+			widths[j] = max(widths[j], displayWidth(c))
+		}
+	}
+	var sb strings.Builder
+	line := func() {
+		sb.WriteString("+")
+		for _, w := range widths {
+			sb.WriteString(strings.Repeat("-", w+2))
+			sb.WriteString("+")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow := func(cells []string, alignNum bool) {
+		sb.WriteString("|")
+		for j, c := range cells {
+			pad := strings.Repeat(" ", widths[j]-displayWidth(c))
+			sb.WriteString(" ")
+			if alignNum && t.numeric[j] {
+				sb.WriteString(pad + c)
+			} else {
+				sb.WriteString(c + pad)
+			}
+			sb.WriteString(" |")
+		}
+		sb.WriteString("\n")
+	}
+	line()
+	writeRow(t.headers, false)
+	line()
+	for i, cells := range t.cells {
+		// 小计/合计行前加分隔线
+		if t.markers[i] != "" && i > 0 && t.markers[i-1] == "" {
+			line()
+		}
+		writeRow(cells, true)
+	}
+	line()
+	return sb.String()
+}
+
+// RenderMarkdown 渲染成 GitHub 风格的 Markdown 表格, 数值列右对齐, 小计/合计行加粗
+func RenderMarkdown(rows []Row, opts ...RenderOption) string {
+	t := buildTable(rows, opts)
+	var sb strings.Builder
+	sb.WriteString("|")
+	for _, h := range t.headers {
+		sb.WriteString(" " + escapeMarkdown(h) + " |")
+	}
+	sb.WriteString("\n|")
+	for j := range t.headers {
+		if t.numeric[j] {
+			sb.WriteString(" ---: |")
+		} else {
+			sb.WriteString(" --- |")
+		}
+	}
+	sb.WriteString("\n")
+	for i, cells := range t.cells {
+		sb.WriteString("|")
+		for _, c := range cells {
+			c = escapeMarkdown(c)
+			if t.markers[i] != "" && c != "" {
+				c = "**" + c + "**"
+			}
+			sb.WriteString(" " + c + " |")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// htmlStyle RenderHTML 内联的样式,保证输出可以直接作为邮件正文
+const htmlStyle = `<style>
+.aggs-table{border-collapse:collapse;font-family:sans-serif;font-size:13px}
+.aggs-table th,.aggs-table td{border:1px solid #ccc;padding:4px 8px}
+.aggs-table th{background:#f0f0f0}
+.aggs-table td.num{text-align:right;font-variant-numeric:tabular-nums}
+.aggs-table tr.subtotal td{background:#fafae0;font-weight:bold}
+.aggs-table tr.total td{background:#f0e6c8;font-weight:bold}
+</style>
+`
+
+// RenderHTML 渲染成自包含(内联样式)的HTML表格; 数值列右对齐, 小计/合计行的class为其标记值
+func RenderHTML(rows []Row, opts ...RenderOption) string {
+	t := buildTable(rows, opts)
+	var sb strings.Builder
+	sb.WriteString(htmlStyle)
+	sb.WriteString("<table class=\"aggs-table\">\n<thead><tr>")
+	for _, h := range t.headers {
+		sb.WriteString("<th>" + html.EscapeString(h) + "</th>")
+	}
+	sb.WriteString("</tr></thead>\n<tbody>\n")
+	for i, cells := range t.cells {
+		if t.markers[i] != "" {
+			sb.WriteString("<tr class=\"" + html.EscapeString(t.markers[i]) + "\">")
+		} else {
+			sb.WriteString("<tr>")
+		}
+		for j, c := range cells {
+			if t.numeric[j] {
+				sb.WriteString("<td class=\"num\">")
+			} else {
+				sb.WriteString("<td>")
+			}
+			sb.WriteString(html.EscapeString(c) + "</td>")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	return sb.String()
+}
+
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// displayWidth 字符串在等宽字体下的显示宽度,中日韩文字及全角字符按2计算
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWide(r) {
+			width += 2
+		} else if r != utf8.RuneError {
+			width++
+		}
+	}
+	return width
+}
+
+func isWide(r rune) bool {
+	return (r >= 0x1100 && r <= 0x115F) ||
+		(r >= 0x2E80 && r <= 0xA4CF) ||
+		(r >= 0xAC00 && r <= 0xD7A3) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0xFE30 && r <= 0xFE4F) ||
+		(r >= 0xFF00 && r <= 0xFF60) ||
+		(r >= 0xFFE0 && r <= 0xFFE6)
+}
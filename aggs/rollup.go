@@ -0,0 +1,125 @@
+package aggs
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// 小计/合计行中 MarkerField 字段的值
+const (
+	RollupSubtotal = "subtotal"
+	RollupTotal    = "total"
+)
+
+// RollupOption 小计/合计行的配置,零值字段使用默认值
+type RollupOption struct {
+	SubtotalLabel string // 小计标签,默认 "小计"
+	TotalLabel    string // 合计标签,默认 "合计"
+	// LabelField 写入标签的字段; 为空时小计行写成 "分组值+小计" 放在当前层级字段, 合计行写在第一个层级字段
+	LabelField  string
+	MarkerField string // 标记字段,值为 RollupSubtotal/RollupTotal, 默认 "_rollup"
+	LevelField  string // 小计所在层级(从0开始,合计为-1), 默认 "_rollup_level"
+	NoTotal     bool   // 不输出合计行
+}
+
+func (o RollupOption) withDefault() RollupOption {
+	if o.SubtotalLabel == "" {
+		o.SubtotalLabel = "小计"
+	}
+	if o.TotalLabel == "" {
+		o.TotalLabel = "合计"
+	}
+	if o.MarkerField == "" {
+		o.MarkerField = "_rollup"
+	}
+	if o.LevelField == "" {
+		o.LevelField = "_rollup_level"
+	}
+	return o
+}
+
+// Rollup 按levels逐层分组,在每个分组后插入小计行,最后插入合计行
+// 分组按首次出现的顺序排列,明细行原样输出; measures 统一转换成decimal.Decimal求和,因此可以混用不同的数值类型
+func Rollup(rows []Row, levels []string, measures []string, opts ...RollupOption) []Row {
+	var opt RollupOption
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	opt = opt.withDefault()
+	res := make([]Row, 0, len(rows)+len(rows)/2+1)
+	total := opt.rollupLevel(&res, rows, levels, 0, measures)
+	if !opt.NoTotal {
+		row := Row{opt.MarkerField: RollupTotal, opt.LevelField: -1}
+		for _, field := range levels {
+			row[field] = nil
+		}
+		if opt.LabelField != "" {
+			row[opt.LabelField] = opt.TotalLabel
+		} else if len(levels) != 0 {
+			row[levels[0]] = opt.TotalLabel
+		}
+		fillSums(row, measures, total)
+		res = append(res, row)
+	}
+	return res
+}
+
+// rollupLevel 递归输出depth层级及以下的明细与小计,返回该批数据的合计
+func (o RollupOption) rollupLevel(res *[]Row, rows []Row, levels []string, depth int, measures []string) map[string]decimal.Decimal {
+	sums := make(map[string]decimal.Decimal, len(measures))
+	if depth == len(levels) {
+		for _, row := range rows {
+			*res = append(*res, row)
+			for _, field := range measures {
+				if v, ok := ToDecimal(row[field]); ok {
+					sums[field] = sums[field].Add(v)
+				}
+			}
+		}
+		return sums
+	}
+	for _, group := range groupRows(rows, levels[depth]) {
+		sub := o.rollupLevel(res, group, levels, depth+1, measures)
+		row := Row{o.MarkerField: RollupSubtotal, o.LevelField: depth}
+		for i, field := range levels {
+			if i <= depth {
+				row[field] = group[0][field]
+			} else {
+				row[field] = nil
+			}
+		}
+		if o.LabelField != "" {
+			row[o.LabelField] = o.SubtotalLabel
+		} else {
+			row[levels[depth]] = NumToString(group[0][levels[depth]]) + o.SubtotalLabel
+		}
+		fillSums(row, measures, sub)
+		*res = append(*res, row)
+		for field, v := range sub {
+			sums[field] = sums[field].Add(v)
+		}
+	}
+	return sums
+}
+
+// groupRows 按字段值分组,保持首次出现的顺序
+func groupRows(rows []Row, field string) [][]Row {
+	idx := make(map[string]int)
+	var groups [][]Row
+	for _, row := range rows {
+		key := groupKey(row, []string{field})
+		i, ok := idx[key]
+		if !ok {
+			i = len(groups)
+			idx[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups
+}
+
+func fillSums(row Row, measures []string, sums map[string]decimal.Decimal) {
+	for _, field := range measures {
+		row[field] = sums[field]
+	}
+}
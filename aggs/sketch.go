@@ -0,0 +1,199 @@
+package aggs
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const defaultSketchAccuracy = 0.01
+
+// Sketch 可合并的分位数草图(DDSketch),估算值的相对误差不超过 Accuracy
+// 各 mr mapper 可以分别构建 Sketch, 序列化后在 reducer 中 Merge
+type Sketch struct {
+	accuracy float64
+	gamma    float64
+	logGamma float64
+	pos      map[int]int64 // 正数桶: 索引 -> 数量
+	neg      map[int]int64 // 负数桶,按绝对值计算索引
+	zero     int64
+	count    int64
+	min      float64
+	max      float64
+}
+
+// sketchJSON Sketch 的序列化格式
+type sketchJSON struct {
+	Accuracy float64       `json:"accuracy"`
+	Count    int64         `json:"count"`
+	Zero     int64         `json:"zero"`
+	Min      float64       `json:"min"`
+	Max      float64       `json:"max"`
+	Pos      map[int]int64 `json:"pos,omitempty"`
+	Neg      map[int]int64 `json:"neg,omitempty"`
+}
+
+// NewSketch accuracy 为相对误差,取值(0,1),非法时使用默认值0.01
+func NewSketch(accuracy float64) *Sketch {
+	if accuracy <= 0 || accuracy >= 1 {
+		accuracy = defaultSketchAccuracy
+	}
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &Sketch{
+		accuracy: accuracy,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		pos:      make(map[int]int64),
+		neg:      make(map[int]int64),
+	}
+}
+
+func (s *Sketch) Accuracy() float64 {
+	return s.accuracy
+}
+
+func (s *Sketch) Count() int64 {
+	return s.count
+}
+
+// Add 加入一个值, NaN 会被忽略, ±Inf 按 ±math.MaxFloat64 计入(避免桶索引溢出,且可以序列化)
+func (s *Sketch) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if math.IsInf(v, 0) {
+		v = math.Copysign(math.MaxFloat64, v)
+	}
+	switch {
+	case v > 0:
+		s.pos[s.index(v)]++
+	case v < 0:
+		s.neg[s.index(-v)]++
+	default:
+		s.zero++
+	}
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+}
+
+// Merge 合并另一个Sketch, 两者的精度必须一致
+func (s *Sketch) Merge(o *Sketch) error {
+	if o == nil || o.count == 0 {
+		return nil
+	}
+	if o.accuracy != s.accuracy {
+		return errors.New("aggs: can't merge sketches with different accuracy")
+	}
+	for i, c := range o.pos {
+		s.pos[i] += c
+	}
+	for i, c := range o.neg {
+		s.neg[i] += c
+	}
+	s.zero += o.zero
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.count += o.count
+	return nil
+}
+
+// Quantile 返回q分位数的估算值,q取值[0,1], 没有数据时返回0
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	rank := int64(q * float64(s.count-1))
+	var seen int64
+	// 负数桶按绝对值从大到小即为数值从小到大
+	negIdx := sortedKeys(s.neg)
+	for i := len(negIdx) - 1; i >= 0; i-- {
+		seen += s.neg[negIdx[i]]
+		if seen > rank {
+			return s.clamp(-s.value(negIdx[i]))
+		}
+	}
+	seen += s.zero
+	if seen > rank {
+		return 0
+	}
+	for _, i := range sortedKeys(s.pos) {
+		seen += s.pos[i]
+		if seen > rank {
+			return s.clamp(s.value(i))
+		}
+	}
+	return s.max
+}
+
+func (s *Sketch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sketchJSON{
+		Accuracy: s.accuracy,
+		Count:    s.count,
+		Zero:     s.zero,
+		Min:      s.min,
+		Max:      s.max,
+		Pos:      s.pos,
+		Neg:      s.neg,
+	})
+}
+
+func (s *Sketch) UnmarshalJSON(data []byte) error {
+	var raw sketchJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = *NewSketch(raw.Accuracy)
+	s.count, s.zero, s.min, s.max = raw.Count, raw.Zero, raw.Min, raw.Max
+	for i, c := range raw.Pos {
+		s.pos[i] = c
+	}
+	for i, c := range raw.Neg {
+		s.neg[i] = c
+	}
+	return nil
+}
+
+func (s *Sketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// value 桶的代表值,保证桶内任意值的相对误差不超过accuracy
+func (s *Sketch) value(i int) float64 {
+	return 2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1)
+}
+
+func (s *Sketch) clamp(v float64) float64 {
+	return math.Max(s.min, math.Min(s.max, v))
+}
+
+func sortedKeys(m map[int]int64) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// quantileField 分位数的输出字段名, 如 latency_p95, latency_p99.9
+func quantileField(field string, q float64) string {
+	// 先舍入到百分位的4位小数,避免 0.07*100 得到 7.000000000000001
+	return field + "_p" + strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64)
+}
@@ -0,0 +1,148 @@
+package aggs
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Stats 单个度量字段的聚合结果: sum/count/avg/min/max
+// Sketch 不为nil时同时维护分位数草图
+type Stats struct {
+	Count  int64           `json:"count"`
+	Sum    decimal.Decimal `json:"sum"`
+	Min    decimal.Decimal `json:"min"`
+	Max    decimal.Decimal `json:"max"`
+	Sketch *Sketch         `json:"sketch,omitempty"`
+}
+
+// AggregateOption 分组聚合的配置
+type AggregateOption struct {
+	GroupBy   []string
+	Measures  []string
+	Quantiles []float64 // 需要输出的分位数,如 0.5, 0.95, 0.99; 非空时为每个度量维护 Sketch
+	Accuracy  float64   // Sketch 的相对误差,默认0.01
+}
+
+// Aggregate 按 GroupBy 分组聚合,分组按首次出现的顺序输出
+// 每组一行: 分组字段, rows, 以及每个度量的 _sum/_count/_avg/_min/_max 和 _pXX 分位数
+func Aggregate(rows []Row, option AggregateOption) []Row {
+	idx := make(map[string]int)
+	var groups []*groupState
+	for _, row := range rows {
+		key := groupKey(row, option.GroupBy)
+		i, ok := idx[key]
+		if !ok {
+			i = len(groups)
+			idx[key] = i
+			p := &groupState{
+				group: make(Row, len(option.GroupBy)),
+				stats: newStats(option.Measures, option.Quantiles, option.Accuracy),
+			}
+			for _, field := range option.GroupBy {
+				p.group[field] = row[field]
+			}
+			groups = append(groups, p)
+		}
+		groups[i].add(row, option.Measures)
+	}
+	res := make([]Row, 0, len(groups))
+	for _, p := range groups {
+		row := Row{"rows": p.rows}
+		for k, v := range p.group {
+			row[k] = v
+		}
+		for field, s := range p.stats {
+			s.fill(row, field, option.Quantiles)
+		}
+		res = append(res, row)
+	}
+	return res
+}
+
+// newStats 为每个度量字段创建Stats, quantiles 非空时带上 Sketch
+func newStats(measures []string, quantiles []float64, accuracy float64) map[string]*Stats {
+	stats := make(map[string]*Stats, len(measures))
+	for _, field := range measures {
+		stats[field] = &Stats{}
+		if len(quantiles) != 0 {
+			stats[field].Sketch = NewSketch(accuracy)
+		}
+	}
+	return stats
+}
+
+// Add 累加一个值
+func (s *Stats) Add(v decimal.Decimal) {
+	if s.Count == 0 || v.LessThan(s.Min) {
+		s.Min = v
+	}
+	if s.Count == 0 || v.GreaterThan(s.Max) {
+		s.Max = v
+	}
+	s.Sum = s.Sum.Add(v)
+	s.Count++
+	if s.Sketch != nil {
+		f, _ := v.Float64()
+		s.Sketch.Add(f)
+	}
+}
+
+// Merge 合并另一个Stats,用于在 mr 的 reducer 中合并各 mapper 的部分结果
+func (s *Stats) Merge(o *Stats) error {
+	if o == nil || o.Count == 0 {
+		return nil
+	}
+	// 只有双方的数据都在 Sketch 中时才合并, 否则丢弃 Sketch, 保证 Sketch 的数量与 Count 一致
+	switch {
+	case o.Sketch == nil:
+		s.Sketch = nil
+	case s.Count == 0:
+		s.Sketch = NewSketch(o.Sketch.Accuracy())
+		if err := s.Sketch.Merge(o.Sketch); err != nil {
+			return err
+		}
+	case s.Sketch != nil:
+		if err := s.Sketch.Merge(o.Sketch); err != nil {
+			return err
+		}
+	}
+	if s.Count == 0 || o.Min.LessThan(s.Min) {
+		s.Min = o.Min
+	}
+	if s.Count == 0 || o.Max.GreaterThan(s.Max) {
+		s.Max = o.Max
+	}
+	s.Sum = s.Sum.Add(o.Sum)
+	s.Count += o.Count
+	return nil
+}
+
+// Avg 平均值,没有数据时返回0
+func (s *Stats) Avg() decimal.Decimal {
+	if s.Count == 0 {
+		return decimal.Zero
+	}
+	return s.Sum.Div(decimal.NewFromInt(s.Count))
+}
+
+// Quantile 返回q分位数的估算值,没有 Sketch 时返回0
+func (s *Stats) Quantile(q float64) float64 {
+	if s.Sketch == nil {
+		return 0
+	}
+	return s.Sketch.Quantile(q)
+}
+
+// fill 将聚合结果以 field_sum/field_count/field_avg/field_min/field_max/field_pXX 的形式写入row
+func (s *Stats) fill(row Row, field string, quantiles []float64) {
+	row[field+"_sum"] = s.Sum
+	row[field+"_count"] = s.Count
+	row[field+"_avg"] = s.Avg()
+	row[field+"_min"] = s.Min
+	row[field+"_max"] = s.Max
+	if s.Sketch == nil {
+		return
+	}
+	for _, q := range quantiles {
+		row[quantileField(field, q)] = s.Sketch.Quantile(q)
+	}
+}
@@ -0,0 +1,306 @@
+package aggs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrWindowClosed = errors.New("aggs: window closed")
+
+// WindowOption 时间窗口聚合的配置
+// Slide 为0或等于 Size 时为滚动窗口,小于 Size 时为滑动窗口
+// 水位线 = 已见到的最大事件时间 - Lateness, 结束时间不晚于水位线的窗口会被关闭并输出
+type WindowOption struct {
+	Size      time.Duration
+	Slide     time.Duration
+	Lateness  time.Duration
+	TimeField string    // AddRow 时读取事件时间的字段
+	GroupBy   []string  // 分组字段,为空时每个时间窗口只输出一个结果
+	Measures  []string  // 需要聚合的度量字段
+	Quantiles []float64 // 需要输出的分位数,非空时为每个度量维护 Sketch
+	Accuracy  float64   // Sketch 的相对误差,默认0.01
+	// OnClose 窗口关闭时的回调; 为nil时结果写入 C() 返回的channel
+	OnClose func(result WindowResult)
+	// Buffer OnClose 为nil时channel的缓冲大小,默认64
+	Buffer int
+}
+
+// WindowResult 一个关闭的窗口(及分组)的聚合结果
+type WindowResult struct {
+	Start time.Time
+	End   time.Time
+	Group Row               // 分组字段的值
+	Rows  int64             // 落入窗口的行数
+	Stats map[string]*Stats // 度量字段 -> 聚合结果
+	// Quantiles ToRow 时输出的分位数
+	Quantiles []float64
+}
+
+// ToRow 将结果展开成Row: window_start, window_end, rows, 分组字段, 以及每个度量的 _sum/_count/_avg/_min/_max/_pXX
+func (r WindowResult) ToRow() Row {
+	row := Row{
+		"window_start": r.Start,
+		"window_end":   r.End,
+		"rows":         r.Rows,
+	}
+	for k, v := range r.Group {
+		row[k] = v
+	}
+	for field, s := range r.Stats {
+		s.fill(row, field, r.Quantiles)
+	}
+	return row
+}
+
+// Window 进程内的流式时间窗口聚合器,并发安全
+type Window struct {
+	mu        sync.Mutex
+	emitMu    sync.Mutex
+	option    WindowOption
+	panes     map[int64]map[string]*groupState // 窗口开始时间 -> 分组key -> 聚合状态
+	watermark time.Time
+	dropped   int64
+	out       chan WindowResult
+	closed    bool
+}
+
+type groupState struct {
+	group Row
+	rows  int64
+	stats map[string]*Stats
+}
+
+func NewWindow(option WindowOption) (*Window, error) {
+	if option.Size <= 0 {
+		return nil, errors.New("aggs: window size must be positive")
+	}
+	if option.Slide <= 0 {
+		option.Slide = option.Size
+	}
+	if option.Slide > option.Size {
+		return nil, errors.New("aggs: window slide can't be greater than size")
+	}
+	if option.Lateness < 0 {
+		option.Lateness = 0
+	}
+	w := &Window{
+		option: option,
+		panes:  make(map[int64]map[string]*groupState),
+	}
+	if option.OnClose == nil {
+		if option.Buffer <= 0 {
+			option.Buffer = 64
+		}
+		w.out = make(chan WindowResult, option.Buffer)
+	}
+	return w, nil
+}
+
+// C 返回接收关闭窗口的channel, 配置了 OnClose 时返回nil
+func (w *Window) C() <-chan WindowResult {
+	return w.out
+}
+
+// Dropped 由于迟到(所属窗口均已关闭)被丢弃的事件数
+func (w *Window) Dropped() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Watermark 当前水位线
+func (w *Window) Watermark() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.watermark
+}
+
+// AddRow 从 TimeField 读取事件时间并加入窗口
+func (w *Window) AddRow(row Row) error {
+	ts, ok := ToTime(row[w.option.TimeField])
+	if !ok {
+		return fmt.Errorf("aggs: field %q is not a time", w.option.TimeField)
+	}
+	return w.Add(ts, row)
+}
+
+// Add 加入一个带时间戳的事件,可能触发窗口关闭
+func (w *Window) Add(ts time.Time, row Row) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWindowClosed
+	}
+	accepted := false
+	for _, start := range w.windowStarts(ts) {
+		end := time.Unix(0, start).Add(w.option.Size)
+		if !w.watermark.IsZero() && !end.After(w.watermark) {
+			continue
+		}
+		w.pane(start, row).add(row, w.option.Measures)
+		accepted = true
+	}
+	if !accepted {
+		w.dropped++
+	}
+	results := w.advance(ts.Add(-w.option.Lateness))
+	w.emit(results)
+	return nil
+}
+
+// Advance 使用处理时间推进水位线,在没有新事件时也能按时关闭窗口
+func (w *Window) Advance(now time.Time) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.emit(w.advance(now.Add(-w.option.Lateness)))
+}
+
+// Flush 关闭并输出所有未关闭的窗口
+func (w *Window) Flush() {
+	w.mu.Lock()
+	w.emit(w.collect(func(int64) bool { return true }))
+}
+
+// Close 输出所有未关闭的窗口并关闭channel, 之后的 Add 返回 ErrWindowClosed
+func (w *Window) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	results := w.collect(func(int64) bool { return true })
+	w.emitMu.Lock()
+	w.mu.Unlock()
+	defer w.emitMu.Unlock()
+	w.send(results)
+	if w.out != nil {
+		close(w.out)
+	}
+}
+
+// windowStarts 计算ts所属的所有窗口的开始时间(纳秒)
+func (w *Window) windowStarts(ts time.Time) []int64 {
+	n := ts.UnixNano()
+	size, slide := int64(w.option.Size), int64(w.option.Slide)
+	last := n - ((n%slide)+slide)%slide
+	res := make([]int64, 0, size/slide)
+	for start := last; start > n-size; start -= slide {
+		res = append(res, start)
+	}
+	return res
+}
+
+func (w *Window) pane(start int64, row Row) *groupState {
+	groups, ok := w.panes[start]
+	if !ok {
+		groups = make(map[string]*groupState)
+		w.panes[start] = groups
+	}
+	key := groupKey(row, w.option.GroupBy)
+	p, ok := groups[key]
+	if !ok {
+		p = &groupState{
+			group: make(Row, len(w.option.GroupBy)),
+			stats: newStats(w.option.Measures, w.option.Quantiles, w.option.Accuracy),
+		}
+		for _, field := range w.option.GroupBy {
+			p.group[field] = row[field]
+		}
+		groups[key] = p
+	}
+	return p
+}
+
+func (p *groupState) add(row Row, measures []string) {
+	p.rows++
+	for _, field := range measures {
+		if v, ok := ToDecimal(row[field]); ok {
+			p.stats[field].Add(v)
+		}
+	}
+}
+
+// advance 推进水位线并收集已关闭的窗口,需持有mu
+func (w *Window) advance(watermark time.Time) []WindowResult {
+	if !watermark.After(w.watermark) {
+		return nil
+	}
+	w.watermark = watermark
+	size := int64(w.option.Size)
+	wm := watermark.UnixNano()
+	return w.collect(func(start int64) bool {
+		return start+size <= wm
+	})
+}
+
+// collect 取出满足条件的窗口,按开始时间和分组排序,需持有mu
+func (w *Window) collect(shouldClose func(start int64) bool) []WindowResult {
+	starts := make([]int64, 0, len(w.panes))
+	for start := range w.panes {
+		if shouldClose(start) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	var results []WindowResult
+	for _, start := range starts {
+		groups := w.panes[start]
+		delete(w.panes, start)
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p := groups[key]
+			results = append(results, WindowResult{
+				Start: time.Unix(0, start),
+				End:   time.Unix(0, start).Add(w.option.Size),
+				Group: p.group,
+				Rows:  p.rows,
+				Stats: p.stats,
+				// 每个窗口共享配置中的分位数
+				Quantiles: w.option.Quantiles,
+			})
+		}
+	}
+	return results
+}
+
+// emit 在释放mu后输出结果; emitMu 保证输出顺序与关闭顺序一致. 调用前需持有mu
+func (w *Window) emit(results []WindowResult) {
+	w.emitMu.Lock()
+	w.mu.Unlock()
+	defer w.emitMu.Unlock()
+	w.send(results)
+}
+
+func (w *Window) send(results []WindowResult) {
+	for _, r := range results {
+		if w.option.OnClose != nil {
+			w.option.OnClose(r)
+		} else {
+			w.out <- r
+		}
+	}
+}
+
+// groupKey 使用分组字段的值拼接成key
+func groupKey(row Row, fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = fmt.Sprintf("%v", deref(row[field]))
+	}
+	return strings.Join(parts, "\x1f")
+}
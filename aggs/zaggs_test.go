@@ -0,0 +1,368 @@
+package aggs
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWindowTumbling(t *testing.T) {
+	var results []WindowResult
+	w, err := NewWindow(WindowOption{
+		Size:     time.Minute,
+		Lateness: 10 * time.Second,
+		GroupBy:  []string{"service"},
+		Measures: []string{"latency"},
+		OnClose:  func(r WindowResult) { results = append(results, r) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Unix(1700000000, 0).Truncate(time.Minute)
+	_ = w.Add(base.Add(1*time.Second), Row{"service": "api", "latency": 10})
+	_ = w.Add(base.Add(20*time.Second), Row{"service": "api", "latency": []byte("30")})
+	_ = w.Add(base.Add(30*time.Second), Row{"service": "db", "latency": 5.5})
+	_ = w.Add(base.Add(65*time.Second), Row{"service": "api", "latency": int64(1)})
+	if len(results) != 0 {
+		t.Fatalf("window closed before watermark passed: %v", results)
+	}
+	// 迟到但仍在允许范围内
+	_ = w.Add(base.Add(50*time.Second), Row{"service": "api", "latency": 2})
+	_ = w.Add(base.Add(75*time.Second), Row{"service": "api", "latency": 1})
+	if len(results) != 2 {
+		t.Fatalf("expect 2 closed windows, got %d", len(results))
+	}
+	api := results[0].Stats["latency"]
+	if results[0].Group["service"] != "api" || api.Count != 3 || api.Sum.String() != "42" || api.Max.String() != "30" || api.Avg().String() != "14" {
+		t.Fatalf("unexpected api stats: %+v", api)
+	}
+	// 所属窗口已关闭,丢弃
+	_ = w.Add(base.Add(59*time.Second), Row{"service": "api", "latency": 2})
+	if w.Dropped() != 1 {
+		t.Fatalf("expect 1 dropped, got %d", w.Dropped())
+	}
+	w.Close()
+	if len(results) != 3 || results[2].Rows != 2 {
+		t.Fatalf("close should flush open windows: %+v", results)
+	}
+	if err := w.Add(base, Row{}); err != ErrWindowClosed {
+		t.Fatalf("expect ErrWindowClosed, got %v", err)
+	}
+}
+
+func TestWindowSliding(t *testing.T) {
+	w, err := NewWindow(WindowOption{
+		Size:      5 * time.Minute,
+		Slide:     time.Minute,
+		TimeField: "ts",
+		Measures:  []string{"v"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Unix(1700000000, 0).Truncate(5 * time.Minute)
+	if err := w.AddRow(Row{"ts": base.Add(30 * time.Second), "v": 1}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	n := 0
+	for r := range w.C() {
+		if r.Stats["v"].Count != 1 {
+			t.Fatalf("unexpected stats: %+v", r.Stats["v"])
+		}
+		n++
+	}
+	if n != 5 {
+		t.Fatalf("event should fall into 5 sliding windows, got %d", n)
+	}
+}
+
+func TestRollup(t *testing.T) {
+	rows := []Row{
+		{"region": "华东", "city": "上海", "amount": 10},
+		{"region": "华东", "city": "杭州", "amount": []byte("2.5")},
+		{"region": "华北", "city": "北京", "amount": 1.5},
+		{"region": "华东", "city": "上海", "amount": int64(3)},
+	}
+	res := Rollup(rows, []string{"region"}, []string{"amount"})
+	// 华东 x3, 华东小计, 华北 x1, 华北小计, 合计
+	if len(res) != 7 {
+		t.Fatalf("expect 7 rows, got %d", len(res))
+	}
+	sub := res[3]
+	if sub["_rollup"] != RollupSubtotal || sub["region"] != "华东小计" || NumToString(sub["amount"]) != "15.5" {
+		t.Fatalf("unexpected subtotal: %v", sub)
+	}
+	total := res[6]
+	if total["_rollup"] != RollupTotal || total["region"] != "合计" || NumToString(total["amount"]) != "17" {
+		t.Fatalf("unexpected total: %v", total)
+	}
+
+	res = Rollup(rows, []string{"region", "city"}, []string{"amount"}, RollupOption{LabelField: "label", NoTotal: true})
+	// 上海 x2, 上海小计, 杭州, 杭州小计, 华东小计, 北京, 北京小计, 华北小计
+	if len(res) != 9 {
+		t.Fatalf("expect 9 rows, got %d", len(res))
+	}
+	if res[2]["label"] != "小计" || res[2]["_rollup_level"] != 1 || NumToString(res[2]["amount"]) != "13" {
+		t.Fatalf("unexpected city subtotal: %v", res[2])
+	}
+	if res[5]["region"] != "华东" || res[5]["city"] != nil || res[5]["_rollup_level"] != 0 {
+		t.Fatalf("unexpected region subtotal: %v", res[5])
+	}
+}
+
+func TestSketch(t *testing.T) {
+	a, b := NewSketch(0.01), NewSketch(0.01)
+	for i := 1; i <= 1000; i++ {
+		if i%2 == 0 {
+			a.Add(float64(i))
+		} else {
+			b.Add(float64(i))
+		}
+	}
+	// 模拟 mapper 序列化后在 reducer 合并
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Sketch
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Merge(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count() != 1000 {
+		t.Fatalf("expect 1000 values, got %d", a.Count())
+	}
+	for _, c := range []struct{ q, want float64 }{{0.5, 500}, {0.95, 950}, {0.99, 990}} {
+		if got := a.Quantile(c.q); math.Abs(got-c.want)/c.want > 0.02 {
+			t.Fatalf("p%v: expect ~%v, got %v", c.q*100, c.want, got)
+		}
+	}
+	if err := a.Merge(NewSketch(0.05)); err != nil {
+		t.Fatalf("merge empty sketch should be ok: %v", err)
+	}
+	a.Add(math.Inf(1))
+	a.Add(math.Inf(-1))
+	if a.Count() != 1002 || a.Quantile(1) != math.MaxFloat64 {
+		t.Fatalf("inf: count %d, max %v", a.Count(), a.Quantile(1))
+	}
+	if _, err = json.Marshal(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// 一方没有 Sketch 时不能只保留另一方的值
+	var plain Stats
+	plain.Add(decimal.NewFromInt(1))
+	withSketch := Stats{Sketch: NewSketch(0.01)}
+	withSketch.Add(decimal.NewFromInt(2))
+	if err = plain.Merge(&withSketch); err != nil || plain.Count != 2 || plain.Sketch != nil {
+		t.Fatalf("merge into stats without sketch: %+v %v", plain, err)
+	}
+	var empty Stats
+	if err = empty.Merge(&withSketch); err != nil || empty.Sketch == nil || empty.Sketch.Count() != empty.Count {
+		t.Fatalf("merge into empty stats: %+v %v", empty, err)
+	}
+}
+
+func TestAggregateQuantiles(t *testing.T) {
+	var rows []Row
+	for i := 1; i <= 100; i++ {
+		rows = append(rows, Row{"service": "api", "latency": i})
+	}
+	res := Aggregate(rows, AggregateOption{
+		GroupBy:   []string{"service"},
+		Measures:  []string{"latency"},
+		Quantiles: []float64{0.07, 0.29, 0.5, 0.57, 0.99},
+	})
+	if len(res) != 1 || res[0]["rows"] != int64(100) {
+		t.Fatalf("unexpected result: %v", res)
+	}
+	if p99 := res[0]["latency_p99"].(float64); math.Abs(p99-99) > 2 {
+		t.Fatalf("unexpected p99: %v", p99)
+	}
+	for _, field := range []string{"latency_p7", "latency_p29", "latency_p50", "latency_p57"} {
+		if _, ok := res[0][field]; !ok {
+			t.Fatalf("missing %s: %v", field, res[0])
+		}
+	}
+
+	// NaN 和 ±Inf 不能转换成decimal, 作为非数值跳过而不是panic
+	res = Aggregate([]Row{{"v": math.Inf(1)}, {"v": math.NaN()}, {"v": float32(math.Inf(-1))}, {"v": 2.5}}, AggregateOption{
+		Measures:  []string{"v"},
+		Quantiles: []float64{0.5},
+	})
+	if len(res) != 1 || res[0]["v_count"] != int64(1) {
+		t.Fatalf("unexpected result with non-finite values: %v", res)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	current := []Row{
+		{"day": "mon", "shop": "a", "gmv": 120},
+		{"day": "mon", "shop": "b", "gmv": []byte("50")},
+		{"day": "mon", "shop": "c", "gmv": 10.5},
+	}
+	previous := []Row{
+		{"shop": "a", "gmv": int64(100)},
+		{"shop": "b", "gmv": 0},
+		{"shop": "d", "gmv": 8},
+	}
+	res := Compare(current, previous, []string{"shop"}, []string{"gmv"}, CompareOption{KeepPrevious: true})
+	if len(res) != 4 {
+		t.Fatalf("expect 4 rows, got %d", len(res))
+	}
+	a := res[0]
+	if NumToString(a["gmv_delta"]) != "20" || NumToString(a["gmv_rate"]) != "0.2" || NumToString(a["gmv_ratio"]) != "1.2" {
+		t.Fatalf("unexpected row a: %v", a)
+	}
+	if res[1]["gmv_rate"] != "-" || NumToString(res[1]["gmv_delta"]) != "50" {
+		t.Fatalf("zero baseline should use placeholder: %v", res[1])
+	}
+	if res[2]["gmv_prev"] != "-" || res[2]["gmv_delta"] != "-" {
+		t.Fatalf("missing baseline should use placeholder: %v", res[2])
+	}
+	if res[3]["shop"] != "d" || NumToString(res[3]["gmv_rate"]) != "-1" {
+		t.Fatalf("unexpected previous only row: %v", res[3])
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	var received [][]Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alerts []Alert
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Error(err)
+		}
+		received = append(received, alerts)
+	}))
+	defer server.Close()
+
+	alerter, err := NewAlerter([]AlertRule{
+		{Name: "high_error_rate", Expr: "error_rate > 0.05", For: 3, GroupBy: []string{"service"}},
+	}, &WebhookNotifier{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := [][]Row{
+		{{"service": "api", "error_rate": 0.1}, {"service": "db", "error_rate": 0.01}},
+		{{"service": "api", "error_rate": 0.2}, {"service": "db", "error_rate": 0.5}},
+		{{"service": "api", "error_rate": 0.3}, {"service": "db", "error_rate": 0.01}},
+		{{"service": "api", "error_rate": 0.3}},
+		{{"service": "api", "error_rate": 0.01}},
+	}
+	var states []AlertState
+	for _, rows := range windows {
+		alerts, err := alerter.Evaluate(context.Background(), rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range alerts {
+			states = append(states, a.State)
+		}
+	}
+	// 第3个窗口 firing, 第4个窗口去重不通知, 第5个窗口 resolved; db 只满足1个窗口不告警
+	if len(states) != 2 || states[0] != AlertFiring || states[1] != AlertResolved {
+		t.Fatalf("unexpected states: %v", states)
+	}
+	if len(received) != 2 || received[0][0].Fingerprint != "high_error_rate{service=api}" {
+		t.Fatalf("unexpected webhook calls: %v", received)
+	}
+	if len(alerter.Active()) != 0 {
+		t.Fatalf("expect no active alerts, got %v", alerter.Active())
+	}
+
+	if _, err := NewAlerter([]AlertRule{{Name: "bad", Expr: "x ~ 1"}}); err == nil {
+		t.Fatal("expect invalid op error")
+	}
+	if _, err := NewAlerter([]AlertRule{{Name: "dup", Expr: "x > 1"}, {Name: "dup", Expr: "y > 1"}}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("expect duplicate name error, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	rows := Rollup([]Row{
+		{"region": "华东", "amount": 1234.5},
+		{"region": "华北", "amount": 10},
+	}, []string{"region"}, []string{"amount"}, RollupOption{NoTotal: true})
+	opt := RenderOption{Columns: []Column{{Field: "region", Header: "地区"}, {Field: "amount", Header: "金额"}}}
+
+	text := RenderText(rows, opt)
+	want := "+----------+----------+\n" +
+		"| 地区     | 金额     |\n" +
+		"+----------+----------+\n" +
+		"| 华东     | 1,234.50 |\n" +
+		"+----------+----------+\n" +
+		"| 华东小计 | 1,234.50 |\n" +
+		"| 华北     |       10 |\n" +
+		"+----------+----------+\n" +
+		"| 华北小计 |    10.00 |\n" +
+		"+----------+----------+\n"
+	if text != want {
+		t.Fatalf("unexpected text table:\n%s", text)
+	}
+
+	md := RenderMarkdown(ModuleRows(map[string]Module{
+		"b": {"count": "2"},
+		"a": {"count": "1,000"},
+	}, "name"))
+	wantMd := "| count | name |\n| ---: | --- |\n| 1,000 | a |\n| 2 | b |\n"
+	if md != wantMd {
+		t.Fatalf("unexpected markdown table:\n%s", md)
+	}
+
+	page := RenderHTML(rows, opt)
+	if !strings.Contains(page, `<tr class="subtotal"><td>华东小计</td><td class="num">1,234.50</td></tr>`) {
+		t.Fatalf("unexpected html table:\n%s", page)
+	}
+}
+
+type scanBase struct {
+	ID      int64 `json:"id"`
+	Created time.Time
+}
+
+type scanDTO struct {
+	scanBase
+	Name    string          `aggs:"name"`
+	Amount  decimal.Decimal `json:"amount,omitempty"`
+	Count   *int            `json:"count"`
+	Rate    float64         `json:"rate"`
+	Enabled bool            `json:"enabled"`
+	Ignore  string          `json:"-"`
+}
+
+func TestStructMapping(t *testing.T) {
+	row := Row{
+		"id":      []byte("42"),
+		"Created": "2024-05-01 10:00:00",
+		"name":    []byte("shop"),
+		"amount":  12.5,
+		"count":   uint64(3),
+		"rate":    "0.25",
+		"enabled": int64(1),
+		"Ignore":  "x",
+	}
+	var dto scanDTO
+	if err := Scan(row, &dto); err != nil {
+		t.Fatal(err)
+	}
+	if dto.ID != 42 || dto.Created.Format(time.DateOnly) != "2024-05-01" || dto.Name != "shop" ||
+		dto.Amount.String() != "12.5" || dto.Count == nil || *dto.Count != 3 || dto.Rate != 0.25 || !dto.Enabled || dto.Ignore != "" {
+		t.Fatalf("unexpected scan result: %+v", dto)
+	}
+	back := FromStruct(&dto)
+	if len(back) != 7 || back["id"] != int64(42) || back["name"] != "shop" {
+		t.Fatalf("unexpected row: %v", back)
+	}
+	if err := Scan(Row{"id": "abc", "rate": "x"}, &dto); err == nil || !strings.Contains(err.Error(), "rate") {
+		t.Fatalf("expect aggregated error, got %v", err)
+	}
+}
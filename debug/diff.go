@@ -0,0 +1,295 @@
+package debug
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ChangeKind 差异类型
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// Change 一处路径级别的差异, 路径形如 Rows[3].Amount, Meta["key"]
+type Change struct {
+	Path string
+	Kind ChangeKind
+	From interface{}
+	To   interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatDiffValue(c.To))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, formatDiffValue(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatDiffValue(c.From), formatDiffValue(c.To))
+	}
+}
+
+// DiffOption 比较配置
+type DiffOption struct {
+	// SkipUnexported 忽略未导出字段
+	SkipUnexported bool
+	// Equal 自定义相等判断,返回 handled=false 时使用默认比较; 默认已处理 decimal.Decimal 和 time.Time
+	Equal func(a, b interface{}) (equal, handled bool)
+}
+
+// Diff 递归比较两个值,返回所有路径级别的差异; 支持结构体,map,切片,数组,指针和接口
+// decimal.Decimal 按数值比较(10.0 == 10), time.Time 按时刻比较(忽略时区)
+func Diff(a, b interface{}, opts ...DiffOption) []Change {
+	var opt DiffOption
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	d := &differ{option: opt, visited: make(map[diffVisit]struct{})}
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.changes
+}
+
+// RenderDiff 将差异渲染成每行一条的可读文本
+func RenderDiff(changes []Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+type differ struct {
+	option  DiffOption
+	changes []Change
+	visited map[diffVisit]struct{}
+}
+
+// diffVisit 正在比较的一对引用; 包含类型和切片长度,共用底层数组的子切片,结构体指针与其首字段指针不会被误认为同一对
+type diffVisit struct {
+	a, b       uintptr
+	typ        reflect.Type
+	alen, blen int
+}
+
+func (d *differ) add(path string, kind ChangeKind, a, b reflect.Value) {
+	if path == "" {
+		path = "."
+	}
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, From: valueOf(a), To: valueOf(b)})
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.add(path, Modified, a, b)
+		}
+		return
+	}
+	// 接口拆开后再比较动态类型
+	if a.Kind() == reflect.Interface || b.Kind() == reflect.Interface {
+		d.diff(path, elem(a), elem(b))
+		return
+	}
+	if a.Type() != b.Type() {
+		d.add(path, Modified, a, b)
+		return
+	}
+	if equal, handled := d.equal(a, b); handled {
+		if !equal {
+			d.add(path, Modified, a, b)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, Modified, a, b)
+			}
+			return
+		}
+		leave, ok := d.enter(a, b)
+		if !ok {
+			return
+		}
+		defer leave()
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if d.option.SkipUnexported && !field.IsExported() {
+				continue
+			}
+			d.diff(joinPath(path, field.Name), a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && (a.IsNil() || b.IsNil()) && a.Len()+b.Len() == 0 {
+			return
+		}
+		if a.Kind() == reflect.Slice {
+			leave, ok := d.enter(a, b)
+			if !ok {
+				return
+			}
+			defer leave()
+		}
+		n := max(a.Len(), b.Len())
+		for i := 0; i < n; i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= a.Len():
+				d.add(p, Added, reflect.Value{}, b.Index(i))
+			case i >= b.Len():
+				d.add(p, Removed, a.Index(i), reflect.Value{})
+			default:
+				d.diff(p, a.Index(i), b.Index(i))
+			}
+		}
+	case reflect.Map:
+		leave, ok := d.enter(a, b)
+		if !ok {
+			return
+		}
+		defer leave()
+		keys := a.MapKeys()
+		for _, key := range b.MapKeys() {
+			if !a.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		sortKeys(keys)
+		for _, key := range keys {
+			p := fmt.Sprintf("%s[%s]", path, quoteKey(key))
+			av, bv := a.MapIndex(key), b.MapIndex(key)
+			switch {
+			case !av.IsValid():
+				d.add(p, Added, av, bv)
+			case !bv.IsValid():
+				d.add(p, Removed, av, bv)
+			default:
+				d.diff(p, av, bv)
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			d.add(path, Modified, a, b)
+		}
+	default:
+		if !a.CanInterface() {
+			if fmt.Sprint(a) != fmt.Sprint(b) {
+				d.add(path, Modified, a, b)
+			}
+			return
+		}
+		if a.Interface() != b.Interface() {
+			d.add(path, Modified, a, b)
+		}
+	}
+}
+
+// equal 自定义相等判断以及 decimal.Decimal, time.Time 的特殊处理
+func (d *differ) equal(a, b reflect.Value) (equal, handled bool) {
+	if !a.CanInterface() {
+		return false, false
+	}
+	ai, bi := a.Interface(), b.Interface()
+	if d.option.Equal != nil {
+		if equal, handled = d.option.Equal(ai, bi); handled {
+			return equal, handled
+		}
+	}
+	switch av := ai.(type) {
+	case decimal.Decimal:
+		return av.Equal(bi.(decimal.Decimal)), true
+	case time.Time:
+		return av.Equal(bi.(time.Time)), true
+	}
+	return false, false
+}
+
+// enter 记录正在比较的一对引用,已在当前路径上(循环引用)时返回false; 比较完成后调用leave移除
+func (d *differ) enter(a, b reflect.Value) (leave func(), ok bool) {
+	key := diffVisit{a: a.Pointer(), b: b.Pointer(), typ: a.Type()}
+	if a.Kind() == reflect.Slice {
+		key.alen, key.blen = a.Len(), b.Len()
+	}
+	if _, exists := d.visited[key]; exists {
+		return nil, false
+	}
+	d.visited[key] = struct{}{}
+	return func() { delete(d.visited, key) }, true
+}
+
+func elem(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Interface {
+		return v.Elem()
+	}
+	return v
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func quoteKey(key reflect.Value) string {
+	key = elem(key)
+	if key.Kind() == reflect.String {
+		return fmt.Sprintf("%q", key.String())
+	}
+	return formatKey(key)
+}
+
+// unexportedValue 未导出字段的值不能 Interface(), 记录其格式化后的文本
+type unexportedValue string
+
+func (u unexportedValue) String() string {
+	return string(u)
+}
+
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if !v.CanInterface() {
+		if v.Kind() == reflect.String {
+			return unexportedValue(fmt.Sprintf("%q", v.String()))
+		}
+		return unexportedValue(fmt.Sprint(v))
+	}
+	return v.Interface()
+}
+
+// formatDiffValue 差异值的单行表示
+func formatDiffValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return fmt.Sprintf("%q", val)
+	case decimal.Decimal:
+		return val.String()
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		return fmt.Sprintf("[]byte(%q)", val)
+	case fmt.Stringer:
+		return val.String()
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		return "&" + formatDiffValue(rv.Elem().Interface())
+	}
+	return fmt.Sprintf("%v", v)
+}
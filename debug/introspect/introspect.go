@@ -0,0 +1,118 @@
+// Package introspect 调试接口: goroutine, pprof, memstats 和 debug 中注册的组件状态
+// 与 debug 分开是因为引用 net/http/pprof 会在 http.DefaultServeMux 上注册无校验的 /debug/pprof/;
+// 只有显式挂载调试接口的程序才引用本包
+package introspect
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/wg00001/wgo-sdk/debug"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+)
+
+// ServerOption 调试接口的配置
+type ServerOption struct {
+	// Auth 访问校验,返回false时响应403; 为nil时只允许本机(loopback)访问
+	Auth func(r *http.Request) bool
+}
+
+// Handler 返回调试接口, 路径:
+//
+//	/goroutines        按调用栈分组的goroutine
+//	/memstats          runtime.MemStats
+//	/components        所有注册组件的状态, /components/{name} 单个组件
+//	/pprof/            net/http/pprof
+func Handler(option ServerOption) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, debug.Goroutines())
+	})
+	mux.HandleFunc("/memstats", func(w http.ResponseWriter, r *http.Request) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		writeJSON(w, http.StatusOK, struct {
+			Goroutines int `json:"goroutines"`
+			runtime.MemStats
+		}{runtime.NumGoroutine(), m})
+	})
+	mux.HandleFunc("/components", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, debug.Components())
+	})
+	mux.HandleFunc("/components/{name}", func(w http.ResponseWriter, r *http.Request) {
+		state, ok := debug.Component(r.PathValue("name"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "component not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, state)
+	})
+	mux.HandleFunc("/pprof/", pprof.Index)
+	mux.HandleFunc("/pprof/{name}", func(w http.ResponseWriter, r *http.Request) {
+		// pprof.Index 依赖固定的 /debug/pprof/ 前缀,挂载在其他路径时需要按名称分发
+		switch name := r.PathValue("name"); name {
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(option, r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Mount 将调试接口挂载到mux的prefix下, 如 Mount(mux, "/debug", option)
+func Mount(mux *http.ServeMux, prefix string, option ServerOption) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux.Handle(prefix+"/", http.StripPrefix(prefix, Handler(option)))
+}
+
+// MountGin 将调试接口挂载到gin路由的prefix下
+// 例如 MountGin(g, "/debug", introspect.ServerOption{Auth: checkToken}) 后访问 /debug/memstats
+func MountGin(router gin.IRouter, prefix string, option ServerOption) {
+	handler := Handler(option)
+	router.Any(strings.TrimSuffix(prefix, "/")+"/*path", func(c *gin.Context) {
+		// 去掉路由前缀后交给 Handler,与 http.StripPrefix 的处理方式一致
+		r := new(http.Request)
+		*r = *c.Request
+		u := *c.Request.URL
+		u.Path, u.RawPath = c.Param("path"), ""
+		r.URL = &u
+		handler.ServeHTTP(c.Writer, r)
+		c.Abort()
+	})
+}
+
+func authorized(option ServerOption, r *http.Request) bool {
+	if option.Auth != nil {
+		return option.Auth(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
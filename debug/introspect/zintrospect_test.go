@@ -0,0 +1,72 @@
+package introspect
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wg00001/wgo-sdk/debug"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	unregister := debug.Register("jobs", func() interface{} { return map[string]int{"running": 2} })
+	defer unregister()
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		go func() { <-block }()
+	}
+
+	mux := http.NewServeMux()
+	Mount(mux, "/debug", ServerOption{Auth: func(r *http.Request) bool { return r.Header.Get("X-Token") == "secret" }})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(path string, token string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("X-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	if code, _ := get("/debug/memstats", ""); code != http.StatusForbidden {
+		t.Fatalf("expect 403, got %d", code)
+	}
+	if code, body := get("/debug/components/jobs", "secret"); code != http.StatusOK || !strings.Contains(body, `"running": 2`) {
+		t.Fatalf("unexpected component: %d %s", code, body)
+	}
+	if code, body := get("/debug/memstats", "secret"); code != http.StatusOK || !strings.Contains(body, `"HeapAlloc"`) {
+		t.Fatalf("unexpected memstats: %d %s", code, body)
+	}
+	if code, _ := get("/debug/pprof/goroutine?debug=1", "secret"); code != http.StatusOK {
+		t.Fatalf("unexpected pprof status: %d", code)
+	}
+	if code, body := get("/debug/goroutines", "secret"); code != http.StatusOK || !strings.Contains(body, `"chan receive"`) {
+		t.Fatalf("unexpected goroutines: %d %s", code, body)
+	}
+}
+
+func TestMountGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	MountGin(g, "/debug/", ServerOption{})
+	req := httptest.NewRequest(http.MethodGet, "/debug/components", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("non-loopback should be rejected, got %d", w.Code)
+	}
+	req.RemoteAddr = "127.0.0.1:1234"
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"runtime"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,11 +1,55 @@
 package debug
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/wg00001/wgo-sdk/pool"
+	"io"
+	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+)
+
+// Config 深度打印的配置, 零值即可使用
+type Config struct {
+	MaxDepth       int    // 最大递归深度(指针,结构体,切片,map每层算一级),0表示不限制; 超过时打印 <max depth>
+	SortKeys       bool   // map 按key排序输出
+	SkipUnexported bool   // 不打印未导出字段
+	Indent         string // 每一级的缩进,默认两个空格
+	TimeLayout     string // time.Time 的输出格式,默认 time.RFC3339Nano
+	// TimeLocation time.Time 输出前转换到该时区,为nil时保持原时区
+	TimeLocation *time.Location
+	// FloatPrecision 浮点数的小数位数,默认6位; 为负数时使用能精确还原的最短表示
+	FloatPrecision int
+}
+
+// defaultConfig PrintDeep/Fdump/Sdump 使用的配置
+var defaultConfig = &Config{SortKeys: true}
+
+// buffers Sdump 和 Print 复用的缓冲区
+var buffers = pool.NewObjects(func() bytes.Buffer { return bytes.Buffer{} }, func(buf *bytes.Buffer) { buf.Reset() })
+
+// maxPooledBuffer 超过该容量的缓冲区不放回,避免打印一次大对象后长期占用内存
+const maxPooledBuffer = 64 << 10
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		buffers.Put(buf)
+	}
+}
+
 func PrintWithCount(val interface{}, count *int) {
 	fmt.Printf("count:%d value:%v\n", *count, val)
 	*count++
@@ -16,77 +60,283 @@ func PrintJSON(val interface{}) {
 	fmt.Println(string(b))
 }
 
-// PrintDeep 递归打印
+// PrintDeep 递归打印到标准输出
 func PrintDeep(val interface{}) {
-	printRecursive(reflect.ValueOf(val), 0)
+	defaultConfig.Print(val)
+}
+
+// Fdump 使用默认配置递归打印到w
+func Fdump(w io.Writer, val interface{}) error {
+	return defaultConfig.Fdump(w, val)
+}
+
+// Sdump 使用默认配置递归打印,返回字符串
+func Sdump(val interface{}) string {
+	return defaultConfig.Sdump(val)
+}
+
+// Print 递归打印到标准输出, 先写入缓冲区再一次性输出
+func (c *Config) Print(val interface{}) {
+	buf := buffers.Get()
+	defer putBuffer(buf)
+	_ = c.Fdump(buf, val)
+	_, _ = os.Stdout.Write(buf.Bytes())
+}
+
+// Sdump 递归打印,返回字符串
+func (c *Config) Sdump(val interface{}) string {
+	buf := buffers.Get()
+	defer putBuffer(buf)
+	_ = c.Fdump(buf, val)
+	return buf.String()
+}
+
+// Fdump 递归打印到w, 返回写入时的错误
+func (c *Config) Fdump(w io.Writer, val interface{}) error {
+	p := &printer{
+		Config:  c,
+		w:       w,
+		visited: make(map[visit]struct{}),
+	}
+	if p.Indent == "" {
+		p.Indent = "  "
+	}
+	if p.TimeLayout == "" {
+		p.TimeLayout = time.RFC3339Nano
+	}
+	p.printValue(reflect.ValueOf(val), 0, 0)
+	return p.err
+}
+
+// visit 当前递归路径上的引用,用于检测循环引用
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type printer struct {
+	*Config
+	w       io.Writer
+	err     error
+	visited map[visit]struct{}
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+// getIndent 根据缩进级别生成缩进
+func (p *printer) getIndent(level int) string {
+	return strings.Repeat(p.Indent, level+1)
+}
+
+// enter 记录引用类型的地址,已在当前路径上时返回false
+func (p *printer) enter(v reflect.Value) (leave func(), ok bool) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if key.ptr == 0 {
+		return func() {}, true
+	}
+	if _, exists := p.visited[key]; exists {
+		return nil, false
+	}
+	p.visited[key] = struct{}{}
+	return func() { delete(p.visited, key) }, true
 }
 
-// printRecursive 是递归打印的辅助函数
-func printRecursive(v reflect.Value, indent int) {
+// printValue 是递归打印的辅助函数
+func (p *printer) printValue(v reflect.Value, indent, depth int) {
 	if !v.IsValid() {
-		fmt.Printf("%s<invalid>\n", getIndent(indent))
+		p.printf("%s<invalid>\n", p.getIndent(indent))
+		return
+	}
+	if p.MaxDepth > 0 && depth > p.MaxDepth {
+		p.printf("%s<max depth: %s>\n", p.getIndent(indent), v.Type())
+		return
+	}
+	if p.printSpecial(v, indent) {
 		return
 	}
 
 	switch v.Kind() {
 	case reflect.Ptr:
-		// 如果是指针，打印指针地址，然后递归处理指向的值
+		// 如果是指针，打印指针类型，然后递归处理指向的值
 		if v.IsNil() {
-			fmt.Printf("%s<nil>\n", getIndent(indent))
-		} else {
-			fmt.Printf("%s* %s\n", getIndent(indent), v.Type())
-			printRecursive(v.Elem(), indent+1)
+			p.printf("%s<nil>\n", p.getIndent(indent))
+			return
 		}
+		leave, ok := p.enter(v)
+		if !ok {
+			p.printf("%s<cycle %s 0x%x>\n", p.getIndent(indent), v.Type(), v.Pointer())
+			return
+		}
+		defer leave()
+		p.printf("%s* %s\n", p.getIndent(indent), v.Type())
+		p.printValue(v.Elem(), indent+1, depth+1)
 	case reflect.Struct:
 		// 打印结构体类型和所有字段
-		fmt.Printf("%s%s {\n", getIndent(indent), v.Type())
+		p.printf("%s%s {\n", p.getIndent(indent), v.Type())
 		for i := 0; i < v.NumField(); i++ {
 			field := v.Type().Field(i)
-			fieldValue := v.Field(i)
-			fmt.Printf("%s%s: ", getIndent(indent+1), field.Name)
-			printRecursive(fieldValue, indent+2)
+			if p.SkipUnexported && !field.IsExported() {
+				continue
+			}
+			p.printf("%s%s: ", p.getIndent(indent+1), field.Name)
+			p.printValue(v.Field(i), indent+2, depth+1)
 		}
-		fmt.Printf("%s}\n", getIndent(indent))
+		p.printf("%s}\n", p.getIndent(indent))
 	case reflect.Slice, reflect.Array:
 		// 打印切片或数组的每个元素
-		fmt.Printf("%s%s [\n", getIndent(indent), v.Type())
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				p.printf("%s%s <nil>\n", p.getIndent(indent), v.Type())
+				return
+			}
+			leave, ok := p.enter(v)
+			if !ok {
+				p.printf("%s<cycle %s 0x%x>\n", p.getIndent(indent), v.Type(), v.Pointer())
+				return
+			}
+			defer leave()
+		}
+		p.printf("%s%s [\n", p.getIndent(indent), v.Type())
 		for i := 0; i < v.Len(); i++ {
-			printRecursive(v.Index(i), indent+1)
+			p.printValue(v.Index(i), indent+1, depth+1)
 		}
-		fmt.Printf("%s]\n", getIndent(indent))
+		p.printf("%s]\n", p.getIndent(indent))
 	case reflect.Map:
 		// 打印键值对
-		fmt.Printf("%s%s {\n", getIndent(indent), v.Type())
-		for _, key := range v.MapKeys() {
-			fmt.Printf("%s%v: ", getIndent(indent+1), key)
-			printRecursive(v.MapIndex(key), indent+2)
+		if v.IsNil() {
+			p.printf("%s%s <nil>\n", p.getIndent(indent), v.Type())
+			return
+		}
+		leave, ok := p.enter(v)
+		if !ok {
+			p.printf("%s<cycle %s 0x%x>\n", p.getIndent(indent), v.Type(), v.Pointer())
+			return
+		}
+		defer leave()
+		p.printf("%s%s {\n", p.getIndent(indent), v.Type())
+		keys := v.MapKeys()
+		if p.SortKeys {
+			sortKeys(keys)
 		}
-		fmt.Printf("%s}\n", getIndent(indent))
+		for _, key := range keys {
+			p.printf("%s%s: ", p.getIndent(indent+1), formatKey(key))
+			p.printValue(v.MapIndex(key), indent+2, depth+1)
+		}
+		p.printf("%s}\n", p.getIndent(indent))
 	case reflect.Interface:
 		// 处理接口中的实际值
 		if v.IsNil() {
-			fmt.Printf("%s<nil>\n", getIndent(indent))
+			p.printf("%s<nil>\n", p.getIndent(indent))
 		} else {
-			fmt.Printf("%s%s\n", getIndent(indent), v.Type())
-			printRecursive(v.Elem(), indent+1)
+			p.printf("%s%s\n", p.getIndent(indent), v.Elem().Type())
+			p.printValue(v.Elem(), indent+1, depth)
 		}
 	case reflect.String:
-		fmt.Printf("%s\"%s\"\n", getIndent(indent), v.String())
+		p.printf("%s%q\n", p.getIndent(indent), v.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		fmt.Printf("%s%d\n", getIndent(indent), v.Int())
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		fmt.Printf("%s%d\n", getIndent(indent), v.Uint())
+		p.printf("%s%d\n", p.getIndent(indent), v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.printf("%s%d\n", p.getIndent(indent), v.Uint())
 	case reflect.Float32, reflect.Float64:
-		fmt.Printf("%s%f\n", getIndent(indent), v.Float())
+		p.printf("%s%s\n", p.getIndent(indent), p.formatFloat(v))
 	case reflect.Bool:
-		fmt.Printf("%s%t\n", getIndent(indent), v.Bool())
+		p.printf("%s%t\n", p.getIndent(indent), v.Bool())
 	default:
 		// 打印其他类型
-		fmt.Printf("%s<%s: %v>\n", getIndent(indent), v.Kind(), v)
+		p.printf("%s<%s: %v>\n", p.getIndent(indent), v.Kind(), v)
 	}
 }
 
-// getIndent 根据缩进级别生成空格
-func getIndent(level int) string {
-	return "  " + fmt.Sprintf("%*s", level*2, "")
+func (p *printer) formatFloat(v reflect.Value) string {
+	bitSize := 64
+	if v.Kind() == reflect.Float32 {
+		bitSize = 32
+	}
+	if p.FloatPrecision < 0 {
+		return strconv.FormatFloat(v.Float(), 'g', -1, bitSize)
+	}
+	precision := p.FloatPrecision
+	if precision == 0 {
+		precision = 6
+	}
+	return strconv.FormatFloat(v.Float(), 'f', precision, bitSize)
+}
+
+// printSpecial 对 time.Time, decimal.Decimal, []byte 使用可读的格式输出
+func (p *printer) printSpecial(v reflect.Value, indent int) bool {
+	switch {
+	case v.Type() == timeType && v.CanInterface():
+		t := v.Interface().(time.Time)
+		if p.TimeLocation != nil {
+			t = t.In(p.TimeLocation)
+		}
+		p.printf("%stime.Time(%s)\n", p.getIndent(indent), t.Format(p.TimeLayout))
+	case v.Type() == decimalType && v.CanInterface():
+		p.printf("%sdecimal(%s)\n", p.getIndent(indent), v.Interface().(decimal.Decimal).String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.IsNil() {
+			return false
+		}
+		b := v.Bytes()
+		if utf8.Valid(b) && isPrintable(b) {
+			p.printf("%s[]byte(%q)\n", p.getIndent(indent), b)
+		} else {
+			p.printf("%s[]byte(0x%s)\n", p.getIndent(indent), hex.EncodeToString(b))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func isPrintable(b []byte) bool {
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatKey map key 的单行表示
+func formatKey(key reflect.Value) string {
+	if key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if key.CanInterface() {
+		return fmt.Sprintf("%v", key.Interface())
+	}
+	return fmt.Sprintf("<%s>", key.Type())
+}
+
+// sortKeys 按key排序: 数字按数值, 其余按字符串表示
+func sortKeys(keys []reflect.Value) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Kind() == reflect.Interface && !a.IsNil() {
+			a = a.Elem()
+		}
+		if b.Kind() == reflect.Interface && !b.IsNil() {
+			b = b.Elem()
+		}
+		if a.Kind() == b.Kind() {
+			switch a.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return a.Int() < b.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return a.Uint() < b.Uint()
+			case reflect.Float32, reflect.Float64:
+				return a.Float() < b.Float()
+			}
+		}
+		return formatKey(a) < formatKey(b)
+	})
 }
@@ -0,0 +1,158 @@
+package debug
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateFunc 组件上报当前状态,返回值会被序列化成JSON
+type StateFunc func() interface{}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]StateFunc)
+)
+
+// Register 注册组件(mr任务,异步CSV导出,数据库连接池等)的状态,同名覆盖; 返回注销函数
+func Register(name string, state StateFunc) (unregister func()) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = state
+	return func() { Unregister(name) }
+}
+
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, name)
+}
+
+// Component 返回单个已注册组件的当前状态
+func Component(name string) (state interface{}, ok bool) {
+	registryMu.RLock()
+	fn, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return fn(), true
+}
+
+// Components 返回所有已注册组件的当前状态
+func Components() map[string]interface{} {
+	registryMu.RLock()
+	states := make(map[string]StateFunc, len(registry))
+	for name, state := range registry {
+		states[name] = state
+	}
+	registryMu.RUnlock()
+	res := make(map[string]interface{}, len(states))
+	for name, state := range states {
+		res[name] = state()
+	}
+	return res
+}
+
+// GoroutineGroup 调用栈相同的一组goroutine
+type GoroutineGroup struct {
+	Count  int            `json:"count"`
+	States map[string]int `json:"states"` // 状态 -> 数量, 如 running, chan receive
+	Stack  []string       `json:"stack"`
+}
+
+// Goroutines 按调用栈分组当前所有goroutine,按数量降序
+func Goroutines() []GoroutineGroup {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	groups := make(map[string]*GoroutineGroup)
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+		if len(lines) == 0 || !strings.HasPrefix(lines[0], "goroutine ") {
+			continue
+		}
+		state := goroutineState(lines[0])
+		stack := make([]string, 0, len(lines)-1)
+		for _, line := range lines[1:] {
+			stack = append(stack, normalizeStackLine(line))
+		}
+		key := strings.Join(stack, "\n")
+		g, ok := groups[key]
+		if !ok {
+			g = &GoroutineGroup{States: make(map[string]int), Stack: stack}
+			groups[key] = g
+		}
+		g.Count++
+		g.States[state]++
+	}
+	res := make([]GoroutineGroup, 0, len(groups))
+	for _, g := range groups {
+		res = append(res, *g)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return strings.Join(res[i].Stack, "") < strings.Join(res[j].Stack, "")
+	})
+	return res
+}
+
+// goroutineState 从 "goroutine 7 [chan receive, 3 minutes]:" 中取出状态
+func goroutineState(header string) string {
+	start, end := strings.Index(header, "["), strings.LastIndex(header, "]")
+	if start < 0 || end <= start {
+		return "unknown"
+	}
+	state := header[start+1 : end]
+	if i := strings.Index(state, ","); i >= 0 {
+		state = state[:i]
+	}
+	return state
+}
+
+// normalizeStackLine 去掉调用参数,PC偏移和goroutine编号,使相同调用栈可以归为一组
+func normalizeStackLine(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.Contains(line, ".go:") {
+		if i := strings.LastIndex(line, " +0x"); i >= 0 {
+			line = line[:i]
+		}
+		return line
+	}
+	if strings.HasPrefix(line, "created by ") {
+		if i := strings.Index(line, " in goroutine "); i >= 0 {
+			line = line[:i]
+		}
+		return line
+	}
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// serverStart 进程启动时间,用于在组件状态中计算运行时长
+var serverStart = time.Now()
+
+func init() {
+	Register("runtime", func() interface{} {
+		return map[string]interface{}{
+			"goroutines": runtime.NumGoroutine(),
+			"uptime":     time.Since(serverStart).String(),
+			"go_version": runtime.Version(),
+		}
+	})
+}
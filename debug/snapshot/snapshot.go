@@ -0,0 +1,100 @@
+// Package snapshot 基于 debug 深度打印的golden文件快照测试
+//
+//	func TestReport(t *testing.T) {
+//		snapshot.Match(t, "daily_report", buildReport())
+//	}
+//
+// 使用 go test -update 重写 testdata/*.golden; 无法传递flag时(如IDE中运行)也可以设置环境变量 UPDATE_SNAPSHOTS=1
+package snapshot
+
+import (
+	"flag"
+	"fmt"
+	"github.com/wg00001/wgo-sdk/debug"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite snapshot golden files in testdata")
+
+// UpdateEnv 值为true(strconv.ParseBool)时与 -update 相同
+const UpdateEnv = "UPDATE_SNAPSHOTS"
+
+// Dir golden文件所在目录,相对于测试运行时的工作目录(即被测包的目录)
+var Dir = "testdata"
+
+// config 快照的序列化配置: map按key排序, 浮点数使用最短可还原表示, 跳过未导出字段, 时间转换为UTC
+var config = &debug.Config{SortKeys: true, SkipUnexported: true, FloatPrecision: -1, TimeLocation: time.UTC}
+
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Serialize 将value序列化成确定性的文本; string 和 []byte 原样输出
+func Serialize(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return config.Sdump(value)
+}
+
+// Match 将value的序列化结果与 testdata/<name>.golden 比较,不一致时报告第一处不同的行
+// 使用 -update 或设置了 UPDATE_SNAPSHOTS 时写入golden文件而不比较
+func Match(t testing.TB, name string, value interface{}) {
+	t.Helper()
+	got := Serialize(value)
+	path := filepath.Join(Dir, unsafeName.ReplaceAllString(name, "_")+".golden")
+	if shouldUpdate() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			t.Fatalf("snapshot %s: golden file %s not found, run go test with -update to create it", name, path)
+		}
+		t.Fatalf("snapshot %s: %v", name, err)
+	}
+	if got != string(want) {
+		t.Errorf("snapshot %s mismatch (%s), run go test with -update to accept:\n%s", name, path, lineDiff(string(want), got))
+	}
+}
+
+func shouldUpdate() bool {
+	if *update {
+		return true
+	}
+	env, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return env
+}
+
+// lineDiff 报告第一处不同的行及其上下文
+func lineDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(wl) && i < len(gl) && wl[i] == gl[i] {
+		i++
+	}
+	var sb strings.Builder
+	for j := max(0, i-2); j < i; j++ {
+		fmt.Fprintf(&sb, "  %4d   %s\n", j+1, wl[j])
+	}
+	if i < len(wl) {
+		fmt.Fprintf(&sb, "  %4d - %s\n", i+1, wl[i])
+	}
+	if i < len(gl) {
+		fmt.Fprintf(&sb, "  %4d + %s\n", i+1, gl[i])
+	}
+	return sb.String()
+}
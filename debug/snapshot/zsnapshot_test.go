@@ -0,0 +1,44 @@
+package snapshot
+
+import (
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+	"time"
+)
+
+type row struct {
+	Shop   string
+	Amount decimal.Decimal
+	Rate   float64
+	Day    time.Time
+	memo   string
+}
+
+func TestMatch(t *testing.T) {
+	value := map[string]interface{}{
+		"rows": []row{
+			{Shop: "a", Amount: decimal.RequireFromString("10.50"), Rate: 0.1, Day: time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600)), memo: "x"},
+		},
+		"count": 1,
+	}
+	Match(t, "report", value)
+	Match(t, "text report", "| shop | amount |\n| a | 10.5 |\n")
+}
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff("a\nb\nc\n", "a\nB\nc\n")
+	if !strings.Contains(diff, "2 - b") || !strings.Contains(diff, "2 + B") {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	old := Dir
+	Dir = t.TempDir()
+	defer func() { Dir = old }()
+	t.Setenv(UpdateEnv, "1")
+	Match(t, "new/case", []int{1})
+	t.Setenv(UpdateEnv, "")
+	Match(t, "new/case", []int{1})
+}
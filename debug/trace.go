@@ -0,0 +1,126 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type spanKey struct{}
+
+// Span 一段计时,通过context嵌套形成调用树; 可以在多个goroutine中创建子Span
+type Span struct {
+	Name  string
+	Start time.Time
+
+	mu       sync.Mutex
+	end      time.Time
+	children []*Span
+}
+
+// Start 开始一个Span并放入返回的context; ctx 中已有Span时作为其子Span
+//
+//	ctx, span := debug.Start(ctx, "query orders")
+//	defer span.End()
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{Name: name, Start: time.Now()}
+	if parent := SpanFromContext(ctx); parent != nil {
+		parent.mu.Lock()
+		parent.children = append(parent.children, span)
+		parent.mu.Unlock()
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext 取出ctx中当前的Span,没有时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// End 结束计时,重复调用只有第一次生效
+func (s *Span) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+}
+
+// Duration 耗时; 未结束的Span返回到当前时刻的耗时
+func (s *Span) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duration()
+}
+
+func (s *Span) duration() time.Duration {
+	if s.end.IsZero() {
+		return time.Since(s.Start)
+	}
+	return s.end.Sub(s.Start)
+}
+
+// Children 子Span的快照
+func (s *Span) Children() []*Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Span(nil), s.children...)
+}
+
+// Tree 返回以此Span为根的耗时树, 每行为 名称 耗时 占父Span的百分比
+func (s *Span) Tree() string {
+	var sb strings.Builder
+	_ = s.Fprint(&sb)
+	return sb.String()
+}
+
+// Fprint 将耗时树写入w
+//
+//	handler 120ms
+//	├── query orders 80ms 66.7%
+//	│   └── scan 10ms 12.5%
+//	└── render 30ms 25.0%
+func (s *Span) Fprint(w io.Writer) error {
+	s.mu.Lock()
+	d, running := s.duration(), s.end.IsZero()
+	s.mu.Unlock()
+	if _, err := fmt.Fprintf(w, "%s %s%s\n", s.Name, d, runningMark(running)); err != nil {
+		return err
+	}
+	return s.fprintChildren(w, "", d)
+}
+
+func (s *Span) fprintChildren(w io.Writer, prefix string, parent time.Duration) error {
+	children := s.Children()
+	for i, child := range children {
+		branch, next := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, next = "└── ", "    "
+		}
+		child.mu.Lock()
+		d, running := child.duration(), child.end.IsZero()
+		child.mu.Unlock()
+		percent := 0.0
+		if parent > 0 {
+			percent = float64(d) / float64(parent) * 100
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s %s %.1f%%%s\n", prefix, branch, child.Name, d, percent, runningMark(running)); err != nil {
+			return err
+		}
+		if err := child.fprintChildren(w, prefix+next, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runningMark(running bool) string {
+	if running {
+		return " (running)"
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name   string
+	Next   *node
+	hidden int
+}
+
+func TestSdump(t *testing.T) {
+	n := &node{Name: "a", hidden: 1}
+	n.Next = n
+	out := (&Config{SkipUnexported: true}).Sdump(n)
+	if !strings.Contains(out, "<cycle *debug.node") || strings.Contains(out, "hidden") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	m := map[string]interface{}{
+		"b": decimal.RequireFromString("10.50"),
+		"a": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"c": []byte("raw"),
+	}
+	want := "  map[string]interface {} {\n" +
+		"    a:       time.Time\n" +
+		"        time.Time(2024-05-01T00:00:00Z)\n" +
+		"    b:       decimal.Decimal\n" +
+		"        decimal(10.5)\n" +
+		"    c:       []uint8\n" +
+		"        []byte(\"raw\")\n" +
+		"  }\n"
+	if out := Sdump(m); out != want {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	var buf bytes.Buffer
+	if err := (&Config{MaxDepth: 1}).Fdump(&buf, [][]int{{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<max depth: int>") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if out := Sdump(complex(1, 2)); !strings.Contains(out, "<complex128: (1+2i)>") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+type report struct {
+	Title string
+	Rows  []reportRow
+	Meta  map[string]interface{}
+}
+
+type reportRow struct {
+	Amount decimal.Decimal
+	Day    time.Time
+}
+
+func TestDiff(t *testing.T) {
+	day := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	a := &report{
+		Title: "daily",
+		Rows:  []reportRow{{Amount: decimal.RequireFromString("10.00"), Day: day}, {Amount: decimal.NewFromInt(1), Day: day}},
+		Meta:  map[string]interface{}{"k": 1, "old": true},
+	}
+	b := &report{
+		Title: "daily",
+		Rows: []reportRow{
+			{Amount: decimal.RequireFromString("10"), Day: day.In(time.FixedZone("CST", 8*3600))},
+			{Amount: decimal.RequireFromString("12.50"), Day: day},
+			{Amount: decimal.Zero, Day: day},
+		},
+		Meta: map[string]interface{}{"k": 2, "new": "x"},
+	}
+	got := RenderDiff(Diff(a, b))
+	want := "~ Rows[1].Amount: 1 -> 12.5\n" +
+		"+ Rows[2]: {0 2024-05-01 08:00:00 +0000 UTC}\n" +
+		"~ Meta[\"k\"]: 1 -> 2\n" +
+		"+ Meta[\"new\"]: \"x\"\n" +
+		"- Meta[\"old\"]: true\n"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Fatalf("expect no changes, got %v", changes)
+	}
+
+	// 未导出字段输出值而不是 <nil>
+	type model struct {
+		ID   int
+		name string
+		rate float64
+	}
+	got = RenderDiff(Diff(model{1, "a", 0.5}, model{1, "b", 0.75}))
+	if got != "~ name: \"a\" -> \"b\"\n~ rate: 0.5 -> 0.75\n" {
+		t.Fatalf("unexpected unexported diff:\n%s", got)
+	}
+
+	// 共用底层数组的子切片, 以及共享(非循环)的子结构都要比较
+	type pair struct{ Head, All []int }
+	arr, brr := []int{1, 2, 3}, []int{1, 2, 9}
+	got = RenderDiff(Diff(pair{arr[:1], arr[:3]}, pair{brr[:1], brr[:3]}))
+	if got != "~ All[2]: 3 -> 9\n" {
+		t.Fatalf("unexpected sub-slice diff:\n%s", got)
+	}
+	x, y := &reportRow{Amount: decimal.NewFromInt(1)}, &reportRow{Amount: decimal.NewFromInt(2)}
+	got = RenderDiff(Diff([]*reportRow{x, x}, []*reportRow{y, y}))
+	if got != "~ [0].Amount: 1 -> 2\n~ [1].Amount: 1 -> 2\n" {
+		t.Fatalf("unexpected shared diff:\n%s", got)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	ctx, root := Start(context.Background(), "handler")
+	qctx, query := Start(ctx, "query")
+	_, scan := Start(qctx, "scan")
+	time.Sleep(2 * time.Millisecond)
+	scan.End()
+	query.End()
+	_, render := Start(ctx, "render")
+	render.End()
+	root.End()
+
+	if SpanFromContext(qctx) != query || len(root.Children()) != 2 {
+		t.Fatalf("unexpected span tree")
+	}
+	lines := strings.Split(strings.TrimSpace(root.Tree()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "handler ") ||
+		!strings.HasPrefix(lines[1], "├── query ") || !strings.HasPrefix(lines[2], "│   └── scan ") ||
+		!strings.HasPrefix(lines[3], "└── render ") || !strings.HasSuffix(lines[1], "%") {
+		t.Fatalf("unexpected tree:\n%s", root.Tree())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	unregister := Register("jobs", func() interface{} { return map[string]int{"running": 2} })
+	if state, ok := Component("jobs"); !ok || state.(map[string]int)["running"] != 2 {
+		t.Fatalf("unexpected component: %v", state)
+	}
+	if _, ok := Components()["runtime"]; !ok {
+		t.Fatalf("runtime component missing: %v", Components())
+	}
+	unregister()
+	if _, ok := Component("jobs"); ok {
+		t.Fatal("component should be unregistered")
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		go func() { <-block }()
+	}
+	found := false
+	for deadline := time.Now().Add(time.Second); !found && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		for _, g := range Goroutines() {
+			if g.Count >= 3 && g.States["chan receive"] >= 3 {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("blocked goroutines should be grouped: %+v", Goroutines())
+	}
+}
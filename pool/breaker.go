@@ -0,0 +1,277 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBreakerOpen = errors.New("pool: circuit breaker is open")
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // 正常放行,统计错误率
+	StateOpen                         // 直接拒绝,冷却结束后进入半开
+	StateHalfOpen                     // 放行少量探测请求,全部成功则关闭,任一失败则重新打开
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOption 熔断器配置,零值字段使用默认值
+type BreakerOption struct {
+	Window              time.Duration // 统计错误率的滚动窗口,默认10s
+	Buckets             int           // 窗口分桶数,默认10
+	MinRequests         int           // 窗口内请求数达到该值才按错误率判断,默认10
+	FailureRate         float64       // 错误率阈值,达到后打开,默认0.5
+	ConsecutiveFailures int           // 连续失败达到该次数立即打开,<=0 不启用
+	Cooldown            time.Duration // 打开后经过该时长进入半开,默认5s
+	HalfOpenProbes      int           // 半开时允许的探测请求数,默认1
+	// IsFailure 判断错误是否计为失败,默认除 context.Canceled 以外的错误都计为失败
+	IsFailure func(err error) bool
+	// OnStateChange 状态变化回调,在锁外同步调用
+	OnStateChange func(from, to BreakerState)
+}
+
+// Breaker 熔断器: 依赖(如只读库)不可用时快速失败,避免每个请求都等待连接超时
+type Breaker struct {
+	option BreakerOption
+
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64 // 每次状态变化加一,用于忽略旧状态下请求的结果
+	openedAt    time.Time
+	consecutive int
+	buckets     []bucket
+	probes      int // 半开时已放行的探测数
+	successes   int // 半开时成功的探测数
+}
+
+type bucket struct {
+	start    time.Time
+	success  int
+	failures int
+}
+
+func NewBreaker(option BreakerOption) *Breaker {
+	if option.Window <= 0 {
+		option.Window = 10 * time.Second
+	}
+	if option.Buckets <= 0 {
+		option.Buckets = 10
+	}
+	if option.MinRequests <= 0 {
+		option.MinRequests = 10
+	}
+	if option.FailureRate <= 0 {
+		option.FailureRate = 0.5
+	}
+	if option.Cooldown <= 0 {
+		option.Cooldown = 5 * time.Second
+	}
+	if option.HalfOpenProbes <= 0 {
+		option.HalfOpenProbes = 1
+	}
+	if option.IsFailure == nil {
+		option.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	return &Breaker{option: option, buckets: make([]bucket, option.Buckets)}
+}
+
+// State 当前状态,打开且冷却结束时返回半开
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	from, to := b.refresh(time.Now())
+	state := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return state
+}
+
+// Allow 申请执行一次调用,熔断时返回 ErrBreakerOpen; 否则调用结束后必须用结果调用done
+func (b *Breaker) Allow() (done func(err error), err error) {
+	now := time.Now()
+	b.mu.Lock()
+	from, to := b.refresh(now)
+	switch b.state {
+	case StateOpen:
+		b.mu.Unlock()
+		b.notify(from, to)
+		return nil, ErrBreakerOpen
+	case StateHalfOpen:
+		if b.probes >= b.option.HalfOpenProbes {
+			b.mu.Unlock()
+			b.notify(from, to)
+			return nil, ErrBreakerOpen
+		}
+		b.probes++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(generation, err) })
+	}, nil
+}
+
+// Do 通过熔断器执行fn
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// Call 通过熔断器执行带返回值的fn
+func Call[T any](b *Breaker, fn func() (T, error)) (T, error) {
+	done, err := b.Allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	val, err := fn()
+	done(err)
+	return val, err
+}
+
+func (b *Breaker) record(generation uint64, err error) {
+	now := time.Now()
+	failed := b.option.IsFailure(err)
+	b.mu.Lock()
+	if generation != b.generation {
+		// 状态已经变化,结果不再有意义
+		b.mu.Unlock()
+		return
+	}
+	var from, to BreakerState
+	switch b.state {
+	case StateClosed:
+		cur := b.bucket(now)
+		if failed {
+			cur.failures++
+			b.consecutive++
+		} else {
+			cur.success++
+			b.consecutive = 0
+		}
+		if b.shouldTrip(now) {
+			from, to = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failed {
+			from, to = b.setState(StateOpen, now)
+		} else if b.successes++; b.successes >= b.option.HalfOpenProbes {
+			from, to = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// shouldTrip 连续失败或窗口内错误率达到阈值; 需持有mu
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	if b.option.ConsecutiveFailures > 0 && b.consecutive >= b.option.ConsecutiveFailures {
+		return true
+	}
+	var total, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.option.Window {
+			total += bk.success + bk.failures
+			failures += bk.failures
+		}
+	}
+	return total >= b.option.MinRequests && float64(failures)/float64(total) >= b.option.FailureRate
+}
+
+// bucket 返回当前时刻所在的分桶,过期的分桶会被重置; 需持有mu
+func (b *Breaker) bucket(now time.Time) *bucket {
+	width := b.option.Window / time.Duration(len(b.buckets))
+	start := now.Truncate(width)
+	cur := &b.buckets[int(start.UnixNano()/int64(width))%len(b.buckets)]
+	if !cur.start.Equal(start) {
+		*cur = bucket{start: start}
+	}
+	return cur
+}
+
+// refresh 打开状态冷却结束后转为半开; 需持有mu
+func (b *Breaker) refresh(now time.Time) (from, to BreakerState) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.option.Cooldown {
+		return b.setState(StateHalfOpen, now)
+	}
+	return b.state, b.state
+}
+
+// setState 切换状态并重置统计; 需持有mu
+func (b *Breaker) setState(state BreakerState, now time.Time) (from, to BreakerState) {
+	from = b.state
+	b.state = state
+	b.generation++
+	b.consecutive = 0
+	b.probes, b.successes = 0, 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+	return from, state
+}
+
+func (b *Breaker) notify(from, to BreakerState) {
+	if from != to && b.option.OnStateChange != nil {
+		b.option.OnStateChange(from, to)
+	}
+}
+
+// BreakerPool 为 Pool 的 Get 和 Open 加上熔断,其他方法直接调用被包装的池
+type BreakerPool[T any] struct {
+	Pool[T]
+	Breaker *Breaker
+}
+
+var _ Pool[*struct{}] = (*BreakerPool[*struct{}])(nil)
+
+// WithBreaker 包装池,依赖不可用时 Get/Open 直接返回 ErrBreakerOpen
+func WithBreaker[T any](p Pool[T], breaker *Breaker) *BreakerPool[T] {
+	return &BreakerPool[T]{Pool: p, Breaker: breaker}
+}
+
+func (p *BreakerPool[T]) Get(ctx context.Context) (T, error) {
+	return Call(p.Breaker, func() (T, error) {
+		return p.Pool.Get(ctx)
+	})
+}
+
+func (p *BreakerPool[T]) Open() (T, error) {
+	return Call(p.Breaker, p.Pool.Open)
+}
+
+// Stats 被包装的池实现了 StatsProvider 时返回其统计数据,否则返回零值
+func (p *BreakerPool[T]) Stats() Stats {
+	if sp, ok := p.Pool.(StatsProvider); ok {
+		return sp.Stats()
+	}
+	return Stats{}
+}
@@ -0,0 +1,146 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ObjectStats 对象池的命中统计
+type ObjectStats struct {
+	Gets   int64 `json:"gets"`
+	Hits   int64 `json:"hits"`   // 复用了池中对象的次数
+	Misses int64 `json:"misses"` // 调用New新建的次数
+	Puts   int64 `json:"puts"`
+}
+
+// HitRate 命中率,没有Get时为0
+func (s ObjectStats) HitRate() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+// Objects 类型安全的 sync.Pool 封装, 池中保存 *T,避免放回时的装箱分配
+//
+//	var rows = pool.NewObjects(func() []string { return make([]string, 0, 16) }, func(s *[]string) { *s = (*s)[:0] })
+//	row := rows.Get()
+//	defer rows.Put(row)
+type Objects[T any] struct {
+	newFn  func() T
+	reset  func(*T)
+	pool   sync.Pool
+	gets   atomic.Int64
+	misses atomic.Int64
+	puts   atomic.Int64
+}
+
+// NewObjects newFn 创建新对象,为nil时使用零值; reset 在放回时重置对象,可以为nil
+func NewObjects[T any](newFn func() T, reset func(*T)) *Objects[T] {
+	return &Objects[T]{newFn: newFn, reset: reset}
+}
+
+// Get 取出一个对象,池为空时新建
+func (o *Objects[T]) Get() *T {
+	o.gets.Add(1)
+	if v, ok := o.pool.Get().(*T); ok {
+		return v
+	}
+	o.misses.Add(1)
+	v := new(T)
+	if o.newFn != nil {
+		*v = o.newFn()
+	}
+	return v
+}
+
+// Put 重置后放回对象,放回后不能再使用v
+func (o *Objects[T]) Put(v *T) {
+	if v == nil {
+		return
+	}
+	if o.reset != nil {
+		o.reset(v)
+	}
+	o.puts.Add(1)
+	o.pool.Put(v)
+}
+
+func (o *Objects[T]) Stats() ObjectStats {
+	gets, misses := o.gets.Load(), o.misses.Load()
+	return ObjectStats{Gets: gets, Hits: gets - misses, Misses: misses, Puts: o.puts.Load()}
+}
+
+// DefaultSizeClasses Bytes 默认的容量分级
+var DefaultSizeClasses = []int{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10}
+
+// Bytes 按容量分级的字节切片池,避免小请求拿到大缓冲区,也避免大缓冲区被小请求长期占用
+type Bytes struct {
+	classes []int
+	pools   []*Objects[[]byte]
+	over    atomic.Int64 // 超过最大分级,不经过池直接分配的次数
+}
+
+// NewBytes classes 为升序的容量分级,为空时使用 DefaultSizeClasses
+func NewBytes(classes ...int) *Bytes {
+	if len(classes) == 0 {
+		classes = DefaultSizeClasses
+	}
+	classes = append([]int(nil), classes...)
+	sort.Ints(classes)
+	b := &Bytes{classes: classes, pools: make([]*Objects[[]byte], len(classes))}
+	for i, size := range classes {
+		size := size
+		b.pools[i] = NewObjects(func() []byte {
+			return make([]byte, 0, size)
+		}, func(buf *[]byte) {
+			*buf = (*buf)[:0]
+		})
+	}
+	return b
+}
+
+// Get 返回长度为0,容量不小于size的切片; size超过最大分级时直接分配
+func (b *Bytes) Get(size int) *[]byte {
+	i := sort.SearchInts(b.classes, size)
+	if i == len(b.classes) {
+		b.over.Add(1)
+		buf := make([]byte, 0, size)
+		return &buf
+	}
+	return b.pools[i].Get()
+}
+
+// Put 按容量放回对应的分级, 容量小于最小分级或超过最大分级的切片直接丢弃
+func (b *Bytes) Put(buf *[]byte) {
+	if buf == nil {
+		return
+	}
+	c := cap(*buf)
+	if c > b.classes[len(b.classes)-1] {
+		return
+	}
+	// 放入容量不超过 c 的最大分级,保证从该分级取出的切片容量足够
+	i := sort.SearchInts(b.classes, c+1) - 1
+	if i < 0 {
+		return
+	}
+	b.pools[i].Put(buf)
+}
+
+// Stats 所有分级的统计之和, 超过最大分级的请求计为未命中
+func (b *Bytes) Stats() ObjectStats {
+	var stats ObjectStats
+	for _, p := range b.pools {
+		s := p.Stats()
+		stats.Gets += s.Gets
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Puts += s.Puts
+	}
+	over := b.over.Load()
+	stats.Gets += over
+	stats.Misses += over
+	return stats
+}
@@ -1,8 +1,16 @@
 package pool
 
+import (
+	"context"
+)
+
+// Pool 池的通用接口; 需要统计数据时断言为 StatsProvider
 type Pool[T any] interface {
 	Init(option Option) error
-	Get() (T, error)
+	// Get 借出一个资源,没有空闲资源且已达上限时阻塞等待,直到ctx结束
+	Get(ctx context.Context) (T, error)
+	// Put 归还 Get 借出的资源
+	Put(item T) error
 	CloseAll()
 	Len() int
 	Open() (T, error)
@@ -0,0 +1,479 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPoolClosed  = errors.New("pool: closed")
+	ErrWaitTimeout = errors.New("pool: wait timeout")
+	ErrNotBorrowed = errors.New("pool: item is not borrowed from this pool")
+)
+
+// Options 通用资源池的配置,除 MaxIdle 外零值字段表示不限制
+type Options[T any] struct {
+	MaxOpen     int           // 最大打开数(空闲+借出),<=0 不限制
+	MaxIdle     int           // 最大空闲数,<=0 时默认为2(不能设置为不保留空闲); 大于 MaxOpen 时取 MaxOpen
+	IdleTimeout time.Duration // 空闲超过该时长的资源会被关闭
+	MaxLifetime time.Duration // 创建超过该时长的资源会被关闭
+	WaitTimeout time.Duration // Get 等待的最长时间,<=0 时只受ctx控制
+	// Close 关闭资源,为nil时直接丢弃
+	Close func(item T) error
+	// Ping 借出前的健康检查,返回error时关闭该资源并重新获取
+	Ping func(item T) error
+}
+
+// Factory 创建新资源
+type Factory[T any] func(ctx context.Context) (T, error)
+
+// Resources 通用的资源池,可以用于 gRPC/HTTP client,文件句柄,TCP连接等
+// T 需要可比较(通常为指针或接口),用于识别归还的资源
+type Resources[T comparable] struct {
+	mu       sync.Mutex
+	factory  Factory[T]
+	option   Options[T]
+	idle     []*resource[T] // 后进先出,优先使用最近归还的资源
+	borrowed map[T]*resource[T]
+	numOpen  int
+	waiters  []chan waitResult[T]
+	closed   bool
+	drained  chan struct{} // CloseAll 后所有借出资源都归还时关闭
+	stop     chan struct{}
+
+	waitCount      int64
+	waitDuration   time.Duration
+	closedIdle     int64
+	closedMaxIdle  int64
+	closedLifetime int64
+	closedHealth   int64
+}
+
+type resource[T any] struct {
+	item     T
+	created  time.Time
+	returned time.Time
+}
+
+// closeReason 资源被关闭的原因,用于统计
+type closeReason int
+
+const (
+	reasonNone closeReason = iota
+	reasonIdle
+	reasonMaxIdle
+	reasonLifetime
+	reasonHealth
+)
+
+// waitResult 等待者收到的结果: 归还的资源, 或者空出的名额(res为nil), 或者池已关闭
+type waitResult[T any] struct {
+	res    *resource[T]
+	closed bool
+}
+
+var _ Pool[*struct{}] = (*Resources[*struct{}])(nil)
+var _ StatsProvider = (*Resources[*struct{}])(nil)
+var _ Option = Options[any]{}
+
+func New[T comparable](factory Factory[T], option Options[T]) *Resources[T] {
+	p := &Resources[T]{
+		factory:  factory,
+		borrowed: make(map[T]*resource[T]),
+		drained:  make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	p.setOption(option)
+	return p
+}
+
+// Init 重新设置配置,option 必须为 Options[T]
+func (p *Resources[T]) Init(option Option) error {
+	opt, ok := option.(Options[T])
+	if !ok {
+		return errors.New("Init Fail: option type fail")
+	}
+	p.setOption(opt)
+	return nil
+}
+
+func (p *Resources[T]) setOption(option Options[T]) {
+	if option.MaxIdle <= 0 {
+		option.MaxIdle = 2
+	}
+	if option.MaxOpen > 0 && option.MaxIdle > option.MaxOpen {
+		option.MaxIdle = option.MaxOpen
+	}
+	p.mu.Lock()
+	startCleaner := p.option.IdleTimeout <= 0 && p.option.MaxLifetime <= 0
+	p.option = option
+	p.mu.Unlock()
+	if startCleaner && (option.IdleTimeout > 0 || option.MaxLifetime > 0) {
+		go p.cleaner()
+	}
+}
+
+// Get 借出一个资源: 优先使用空闲资源(借出前做健康检查), 未达上限时新建, 否则排队等待
+func (p *Resources[T]) Get(ctx context.Context) (T, error) {
+	var zero T
+	p.mu.Lock()
+	waitTimeout := p.option.WaitTimeout
+	p.mu.Unlock()
+	if waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return zero, ErrPoolClosed
+		}
+		res, expired := p.popIdle()
+		if res != nil || len(expired) > 0 {
+			p.mu.Unlock()
+			for _, e := range expired {
+				p.closeItem(e.item)
+			}
+			if res != nil {
+				if item, ok := p.checkout(res); ok {
+					return item, nil
+				}
+			}
+			continue
+		}
+		if p.option.MaxOpen <= 0 || p.numOpen < p.option.MaxOpen {
+			p.numOpen++
+			p.mu.Unlock()
+			return p.open(ctx)
+		}
+		ch := make(chan waitResult[T], 1)
+		p.waiters = append(p.waiters, ch)
+		p.waitCount++
+		p.mu.Unlock()
+
+		start := time.Now()
+		select {
+		case r := <-ch:
+			p.addWait(start)
+			if r.closed {
+				return zero, ErrPoolClosed
+			}
+			if r.res == nil {
+				// 空出的名额已经计入 numOpen
+				return p.open(ctx)
+			}
+			if item, ok := p.checkout(r.res); ok {
+				return item, nil
+			}
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.waitDuration += time.Since(start)
+			removed := p.removeWaiter(ch)
+			p.mu.Unlock()
+			if !removed {
+				// 已经被分配了资源或名额,转交给其他等待者; 池关闭的通知没有占用名额,直接丢弃
+				if r := <-ch; !r.closed {
+					p.handOff(r)
+				}
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return zero, ErrWaitTimeout
+			}
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Put 归还资源; 池已关闭或空闲数已满时关闭该资源
+func (p *Resources[T]) Put(item T) error {
+	p.mu.Lock()
+	res, ok := p.borrowed[item]
+	if !ok {
+		p.mu.Unlock()
+		return ErrNotBorrowed
+	}
+	delete(p.borrowed, item)
+	res.returned = time.Now()
+	p.mu.Unlock()
+	p.handOff(waitResult[T]{res: res})
+	return nil
+}
+
+// Release 关闭并丢弃一个借出的资源(例如调用时发现连接已损坏),空出的名额交给等待者
+func (p *Resources[T]) Release(item T) error {
+	p.mu.Lock()
+	res, ok := p.borrowed[item]
+	if !ok {
+		p.mu.Unlock()
+		return ErrNotBorrowed
+	}
+	delete(p.borrowed, item)
+	p.count(reasonHealth, 1)
+	p.mu.Unlock()
+	p.discard(res)
+	return nil
+}
+
+// CloseAll 关闭池: 关闭所有空闲资源,唤醒等待者,并等待所有借出的资源归还后返回
+func (p *Resources[T]) CloseAll() {
+	_ = p.Close(context.Background())
+}
+
+// Close 同 CloseAll, 但最多等待到ctx结束
+func (p *Resources[T]) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.stop)
+		for _, ch := range p.waiters {
+			ch <- waitResult[T]{closed: true}
+		}
+		p.waiters = nil
+		idle := p.idle
+		p.idle = nil
+		p.numOpen -= len(idle)
+		p.checkDrained()
+		p.mu.Unlock()
+		for _, res := range idle {
+			p.closeItem(res.item)
+		}
+	} else {
+		p.mu.Unlock()
+	}
+	select {
+	case <-p.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats 当前的统计数据
+func (p *Resources[T]) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{
+		MaxOpen:        max(p.option.MaxOpen, 0),
+		Open:           p.numOpen,
+		Idle:           len(p.idle),
+		InUse:          len(p.borrowed),
+		WaitCount:      p.waitCount,
+		WaitDuration:   p.waitDuration,
+		ClosedIdle:     p.closedIdle,
+		ClosedMaxIdle:  p.closedMaxIdle,
+		ClosedLifetime: p.closedLifetime,
+		ClosedHealth:   p.closedHealth,
+	}
+}
+
+// Len 当前打开的资源数(空闲+借出)
+func (p *Resources[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.numOpen
+}
+
+// Open 直接使用factory创建一个不受池管理的资源
+func (p *Resources[T]) Open() (T, error) {
+	return p.factory(context.Background())
+}
+
+// open 新建资源并借出,调用前已占用 numOpen 名额
+func (p *Resources[T]) open(ctx context.Context) (T, error) {
+	item, err := p.factory(ctx)
+	if err != nil {
+		p.handOff(waitResult[T]{})
+		var zero T
+		return zero, err
+	}
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.borrowed[item] = &resource[T]{item: item, created: now, returned: now}
+	return item, nil
+}
+
+// checkout 健康检查通过后借出,否则关闭该资源
+func (p *Resources[T]) checkout(res *resource[T]) (T, bool) {
+	if p.option.Ping != nil {
+		if err := p.option.Ping(res.item); err != nil {
+			p.mu.Lock()
+			p.count(reasonHealth, 1)
+			p.mu.Unlock()
+			p.discard(res)
+			var zero T
+			return zero, false
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.borrowed[res.item] = res
+	return res.item, true
+}
+
+// handOff 将归还的资源或空出的名额(r.res为nil)交给第一个等待者,没有等待者时放回空闲列表或释放名额
+func (p *Resources[T]) handOff(r waitResult[T]) {
+	p.mu.Lock()
+	if r.res != nil {
+		reason := p.expired(r.res, time.Now())
+		if p.closed || reason != reasonNone {
+			p.count(reason, 1)
+			p.mu.Unlock()
+			p.discard(r.res)
+			return
+		}
+	}
+	if len(p.waiters) > 0 {
+		ch := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		p.mu.Unlock()
+		ch <- r
+		return
+	}
+	var toClose *resource[T]
+	if r.res == nil {
+		p.numOpen--
+	} else if len(p.idle) < p.option.MaxIdle {
+		p.idle = append(p.idle, r.res)
+	} else {
+		p.numOpen--
+		p.count(reasonMaxIdle, 1)
+		toClose = r.res
+	}
+	p.checkDrained()
+	p.mu.Unlock()
+	if toClose != nil {
+		p.closeItem(toClose.item)
+	}
+}
+
+// discard 关闭资源并把名额交给等待者
+func (p *Resources[T]) discard(res *resource[T]) {
+	p.closeItem(res.item)
+	p.handOff(waitResult[T]{})
+}
+
+func (p *Resources[T]) closeItem(item T) {
+	if p.option.Close != nil {
+		_ = p.option.Close(item)
+	}
+}
+
+// popIdle 取出一个未过期的空闲资源,同时移出遇到的过期资源交给调用方关闭; 需持有mu
+func (p *Resources[T]) popIdle() (res *resource[T], expired []*resource[T]) {
+	now := time.Now()
+	for len(p.idle) > 0 {
+		res = p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		reason := p.expired(res, now)
+		if reason == reasonNone {
+			return res, expired
+		}
+		p.count(reason, 1)
+		p.numOpen--
+		expired = append(expired, res)
+	}
+	return nil, expired
+}
+
+// expired 是否超过生命周期或空闲时长,返回对应的关闭原因; 需持有mu
+func (p *Resources[T]) expired(res *resource[T], now time.Time) closeReason {
+	if p.option.MaxLifetime > 0 && now.Sub(res.created) >= p.option.MaxLifetime {
+		return reasonLifetime
+	}
+	if p.option.IdleTimeout > 0 && now.Sub(res.returned) >= p.option.IdleTimeout {
+		return reasonIdle
+	}
+	return reasonNone
+}
+
+// count 累加关闭原因的计数; 需持有mu
+func (p *Resources[T]) count(reason closeReason, n int64) {
+	switch reason {
+	case reasonIdle:
+		p.closedIdle += n
+	case reasonMaxIdle:
+		p.closedMaxIdle += n
+	case reasonLifetime:
+		p.closedLifetime += n
+	case reasonHealth:
+		p.closedHealth += n
+	}
+}
+
+func (p *Resources[T]) addWait(start time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.waitDuration += time.Since(start)
+}
+
+// removeWaiter 从等待队列移除,返回false表示已经被唤醒; 需持有mu
+func (p *Resources[T]) removeWaiter(ch chan waitResult[T]) bool {
+	for i, w := range p.waiters {
+		if w == ch {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// checkDrained 关闭后所有资源都已归还时通知 Close; 需持有mu
+func (p *Resources[T]) checkDrained() {
+	if p.closed && len(p.borrowed) == 0 && p.numOpen <= 0 {
+		select {
+		case <-p.drained:
+		default:
+			close(p.drained)
+		}
+	}
+}
+
+// cleaner 定期关闭过期的空闲资源
+func (p *Resources[T]) cleaner() {
+	ticker := time.NewTicker(p.cleanInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		var expired []*resource[T]
+		p.mu.Lock()
+		alive := p.idle[:0]
+		for _, res := range p.idle {
+			if reason := p.expired(res, now); reason != reasonNone {
+				p.count(reason, 1)
+				expired = append(expired, res)
+			} else {
+				alive = append(alive, res)
+			}
+		}
+		p.idle = alive
+		p.numOpen -= len(expired)
+		p.mu.Unlock()
+		for _, res := range expired {
+			p.closeItem(res.item)
+		}
+	}
+}
+
+func (p *Resources[T]) cleanInterval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	interval := p.option.IdleTimeout
+	if interval <= 0 || (p.option.MaxLifetime > 0 && p.option.MaxLifetime < interval) {
+		interval = p.option.MaxLifetime
+	}
+	interval /= 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"time"
+)
+
+// Stats 池的运行统计,用于根据生产数据调整池大小
+type Stats struct {
+	MaxOpen int `json:"max_open"` // 最大打开数,0 表示不限制
+
+	Open  int `json:"open"`   // 当前打开数(空闲+借出)
+	Idle  int `json:"idle"`   // 空闲数
+	InUse int `json:"in_use"` // 借出数
+
+	WaitCount    int64         `json:"wait_count"`    // 需要排队等待的 Get 次数
+	WaitDuration time.Duration `json:"wait_duration"` // 排队等待的总时长
+
+	ClosedIdle     int64 `json:"closed_idle"`     // 因空闲超时关闭的数量
+	ClosedMaxIdle  int64 `json:"closed_max_idle"` // 归还时空闲数已满而关闭的数量
+	ClosedLifetime int64 `json:"closed_lifetime"` // 因超过最大生命周期关闭的数量
+	ClosedHealth   int64 `json:"closed_health"`   // 因健康检查失败或 Release 关闭的数量
+}
+
+// StatsProvider 可以提供统计数据的池
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// Metrics 将统计数据展开成 指标名 -> 数值, 便于对接 prometheus, statsd 等, 时长单位为秒
+func (s Stats) Metrics() map[string]float64 {
+	return map[string]float64{
+		"max_open":              float64(s.MaxOpen),
+		"open":                  float64(s.Open),
+		"idle":                  float64(s.Idle),
+		"in_use":                float64(s.InUse),
+		"wait_count":            float64(s.WaitCount),
+		"wait_duration_seconds": s.WaitDuration.Seconds(),
+		"closed_idle":           float64(s.ClosedIdle),
+		"closed_max_idle":       float64(s.ClosedMaxIdle),
+		"closed_lifetime":       float64(s.ClosedLifetime),
+		"closed_health":         float64(s.ClosedHealth),
+	}
+}
+
+// Exporter 接收池的统计数据
+type Exporter func(name string, stats Stats)
+
+// Report 每隔interval把池的统计数据交给exporter,返回停止函数
+//
+//	stop := pool.Report("grpc-user", p, 10*time.Second, func(name string, s pool.Stats) {
+//		for k, v := range s.Metrics() {
+//			gauge.WithLabelValues(name, k).Set(v)
+//		}
+//	})
+//
+// 在调试接口中查看: debug.Register("pool.grpc-user", func() interface{} { return p.Stats() })
+func Report(name string, p StatsProvider, interval time.Duration, exporter Exporter) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				exporter(name, p.Stats())
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,280 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull     = errors.New("pool: worker queue is full")
+	ErrWorkersClosed = errors.New("pool: workers are shut down")
+)
+
+// RejectPolicy 任务队列已满时的处理方式
+type RejectPolicy int
+
+const (
+	RejectBlock      RejectPolicy = iota // 阻塞直到队列有空位或ctx结束
+	RejectError                          // 立即返回 ErrQueueFull
+	RejectCallerRuns                     // 在提交任务的goroutine中直接执行
+)
+
+// WorkersOption goroutine池的配置
+type WorkersOption struct {
+	MinWorkers  int           // 常驻的goroutine数
+	MaxWorkers  int           // 最大goroutine数,<=0 时为 runtime.NumCPU()
+	QueueSize   int           // 等待队列长度,所有goroutine都在忙时任务在此排队
+	IdleTimeout time.Duration // 超过 MinWorkers 的goroutine空闲该时长后退出,默认1分钟
+	Reject      RejectPolicy
+}
+
+// PanicError 任务panic时返回的错误
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool: task panic: %v", e.Value)
+}
+
+// Workers 有界的goroutine池,用于在接口中异步执行后台任务(如生成CSV),避免每个请求启动一个goroutine
+type Workers struct {
+	option  WorkersOption
+	queue   chan func()
+	mu      sync.Mutex
+	running int
+	idle    int
+	closed  bool
+	pending sync.WaitGroup // 已接受但未执行完的任务
+	quit    chan struct{}
+	once    sync.Once
+}
+
+func NewWorkers(option WorkersOption) *Workers {
+	if option.MaxWorkers <= 0 {
+		option.MaxWorkers = runtime.NumCPU()
+	}
+	if option.MinWorkers > option.MaxWorkers {
+		option.MinWorkers = option.MaxWorkers
+	}
+	if option.QueueSize < 0 {
+		option.QueueSize = 0
+	}
+	if option.IdleTimeout <= 0 {
+		option.IdleTimeout = time.Minute
+	}
+	w := &Workers{
+		option: option,
+		queue:  make(chan func(), option.QueueSize),
+		quit:   make(chan struct{}),
+	}
+	w.mu.Lock()
+	for i := 0; i < option.MinWorkers; i++ {
+		w.running++
+		go w.work(nil)
+	}
+	w.mu.Unlock()
+	return w
+}
+
+// Future 异步任务的结果
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// Done 任务结束时关闭
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get 等待任务结束并返回结果,ctx结束时返回ctx.Err()(任务仍会继续执行)
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Submit 提交任务,返回的 Future 可以获取结果; 任务panic时以 *PanicError 作为错误返回
+// 任务开始执行前ctx已结束时不再执行,结果为ctx.Err()
+//
+//	f, err := pool.Submit(ctx, workers, func(ctx context.Context) (int, error) { ... })
+//	n, err := f.Get(ctx)
+func Submit[T any](ctx context.Context, w *Workers, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	f := &Future[T]{done: make(chan struct{})}
+	task := func() {
+		defer close(f.done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		if err := ctx.Err(); err != nil {
+			f.err = err
+			return
+		}
+		f.val, f.err = fn(ctx)
+	}
+	if err := w.submit(ctx, task); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Go 提交不需要返回值的任务
+func (w *Workers) Go(ctx context.Context, fn func(ctx context.Context) error) (*Future[struct{}], error) {
+	return Submit(ctx, w, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+}
+
+func (w *Workers) submit(ctx context.Context, task func()) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWorkersClosed
+	}
+	w.pending.Add(1)
+	task = w.track(task)
+	if w.idle == 0 && w.running < w.option.MaxWorkers {
+		w.running++
+		w.mu.Unlock()
+		go w.work(task)
+		return nil
+	}
+	select {
+	case w.queue <- task:
+		w.mu.Unlock()
+		return nil
+	default:
+	}
+	w.mu.Unlock()
+
+	switch w.option.Reject {
+	case RejectError:
+		w.pending.Done()
+		return ErrQueueFull
+	case RejectCallerRuns:
+		task()
+		return nil
+	default:
+		select {
+		case w.queue <- task:
+			w.ensureWorker()
+			return nil
+		case <-ctx.Done():
+			w.pending.Done()
+			return ctx.Err()
+		}
+	}
+}
+
+// track 任务结束时减少pending计数
+func (w *Workers) track(task func()) func() {
+	return func() {
+		defer w.pending.Done()
+		task()
+	}
+}
+
+// ensureWorker 阻塞入队期间goroutine可能都已空闲退出,确保至少有一个goroutine消费队列
+func (w *Workers) ensureWorker() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running == 0 {
+		w.running++
+		go w.work(nil)
+	}
+}
+
+func (w *Workers) work(task func()) {
+	timer := time.NewTimer(w.option.IdleTimeout)
+	defer timer.Stop()
+	for {
+		if task != nil {
+			task()
+			task = nil
+		}
+		w.mu.Lock()
+		w.idle++
+		w.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(w.option.IdleTimeout)
+		select {
+		case task = <-w.queue:
+			w.mu.Lock()
+			w.idle--
+			w.mu.Unlock()
+		case <-w.quit:
+			w.exit()
+			return
+		case <-timer.C:
+			w.mu.Lock()
+			// 队列中还有任务或者不超过常驻数时继续等待
+			if len(w.queue) > 0 || w.running <= w.option.MinWorkers {
+				w.idle--
+				w.mu.Unlock()
+				continue
+			}
+			w.running--
+			w.idle--
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (w *Workers) exit() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running--
+	w.idle--
+}
+
+// Running 当前的goroutine数
+func (w *Workers) Running() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.running
+}
+
+// Queued 排队中的任务数
+func (w *Workers) Queued() int {
+	return len(w.queue)
+}
+
+// Shutdown 停止接受新任务,等待已提交的任务(包括队列中的)执行完毕后退出所有goroutine
+// ctx结束时返回ctx.Err(),剩余的任务仍会在后台执行完
+func (w *Workers) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		w.pending.Wait()
+		w.once.Do(func() { close(w.quit) })
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,360 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type conn struct {
+	id     int64
+	closed atomic.Bool
+	broken atomic.Bool
+}
+
+func newConnPool(option Options[*conn]) (*Resources[*conn], *atomic.Int64) {
+	var opened atomic.Int64
+	option.Close = func(c *conn) error {
+		c.closed.Store(true)
+		return nil
+	}
+	if option.Ping == nil {
+		option.Ping = func(c *conn) error {
+			if c.broken.Load() {
+				return errors.New("broken")
+			}
+			return nil
+		}
+	}
+	p := New(func(ctx context.Context) (*conn, error) {
+		return &conn{id: opened.Add(1)}, nil
+	}, option)
+	return p, &opened
+}
+
+func TestResources(t *testing.T) {
+	p, opened := newConnPool(Options[*conn]{MaxOpen: 2, MaxIdle: 1, WaitTimeout: 50 * time.Millisecond})
+	ctx := context.Background()
+	a, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.Get(ctx)
+	if p.Len() != 2 {
+		t.Fatalf("len = %d", p.Len())
+	}
+	// 已达上限,等待超时
+	if _, err := p.Get(ctx); !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expect wait timeout, got %v", err)
+	}
+
+	// 等待者直接拿到归还的资源
+	got := make(chan *conn)
+	go func() {
+		c, _ := p.Get(ctx)
+		got <- c
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Put(a); err != nil {
+		t.Fatal(err)
+	}
+	if c := <-got; c != a {
+		t.Fatalf("expect handed off conn %d, got %v", a.id, c)
+	}
+	if err := p.Put(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(a); !errors.Is(err, ErrNotBorrowed) {
+		t.Fatalf("double put: %v", err)
+	}
+
+	// 健康检查失败的资源被关闭并重新创建
+	a.broken.Store(true)
+	c, _ := p.Get(ctx)
+	if c == a || !a.closed.Load() || opened.Load() != 3 {
+		t.Fatalf("broken conn reused: %d, opened %d", c.id, opened.Load())
+	}
+
+	// Release 丢弃资源并空出名额
+	_ = p.Release(c)
+	if !c.closed.Load() || p.Len() != 1 {
+		t.Fatalf("release: closed %v len %d", c.closed.Load(), p.Len())
+	}
+
+	// CloseAll 等待借出的资源归还
+	done := make(chan struct{})
+	go func() {
+		p.CloseAll()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("CloseAll returned before borrowed conn was put back")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := p.Get(ctx); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("get after close: %v", err)
+	}
+	_ = p.Put(b)
+	<-done
+	if !b.closed.Load() || p.Len() != 0 {
+		t.Fatalf("after close: closed %v len %d", b.closed.Load(), p.Len())
+	}
+}
+
+func TestResourcesExpire(t *testing.T) {
+	p, _ := newConnPool(Options[*conn]{IdleTimeout: 20 * time.Millisecond, MaxLifetime: time.Hour})
+	defer p.CloseAll()
+	ctx := context.Background()
+	a, _ := p.Get(ctx)
+	_ = p.Put(a)
+	time.Sleep(30 * time.Millisecond)
+	b, _ := p.Get(ctx)
+	if b == a || !a.closed.Load() {
+		t.Fatal("idle conn should expire")
+	}
+	_ = p.Put(b)
+
+	p2, _ := newConnPool(Options[*conn]{MaxLifetime: 20 * time.Millisecond})
+	defer p2.CloseAll()
+	c, _ := p2.Get(ctx)
+	time.Sleep(30 * time.Millisecond)
+	_ = p2.Put(c)
+	if !c.closed.Load() || p2.Len() != 0 {
+		t.Fatal("conn over max lifetime should be closed on put")
+	}
+}
+
+func TestResourcesCancel(t *testing.T) {
+	p, _ := newConnPool(Options[*conn]{MaxOpen: 1})
+	defer p.CloseAll()
+	a, _ := p.Get(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := p.Get(ctx)
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	_ = p.Put(a)
+	if b, err := p.Get(context.Background()); err != nil || b != a {
+		t.Fatalf("get after cancel: %v %v", b, err)
+	} else {
+		_ = p.Put(b)
+	}
+}
+
+func TestResourcesStats(t *testing.T) {
+	p, _ := newConnPool(Options[*conn]{MaxOpen: 2, MaxIdle: 1})
+	ctx := context.Background()
+	a, _ := p.Get(ctx)
+	b, _ := p.Get(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = p.Put(a)
+	}()
+	c, _ := p.Get(ctx) // 排队等待 a 归还
+	s := p.Stats()
+	if s.Open != 2 || s.InUse != 2 || s.Idle != 0 || s.WaitCount != 1 || s.WaitDuration < 5*time.Millisecond {
+		t.Fatalf("stats: %+v", s)
+	}
+	_ = p.Put(b)
+	_ = p.Put(c) // 空闲已满
+	c2, _ := p.Get(ctx)
+	c2.broken.Store(true)
+	_ = p.Put(c2)
+	d, _ := p.Get(ctx) // 健康检查失败
+	_ = p.Release(d)
+	s = p.Stats()
+	if s.ClosedMaxIdle != 1 || s.ClosedHealth != 2 || s.Open != 0 || s.Idle != 0 {
+		t.Fatalf("stats: %+v", s)
+	}
+	if m := s.Metrics(); m["closed_health"] != 2 || m["max_open"] != 2 {
+		t.Fatalf("metrics: %v", m)
+	}
+	p.CloseAll()
+}
+
+func TestWorkers(t *testing.T) {
+	w := NewWorkers(WorkersOption{MaxWorkers: 2, QueueSize: 1, Reject: RejectError})
+	ctx := context.Background()
+	release := make(chan struct{})
+	var futures []*Future[int]
+	for i := 0; i < 3; i++ {
+		i := i
+		f, err := Submit(ctx, w, func(ctx context.Context) (int, error) {
+			<-release
+			return i * 10, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+	if w.Running() != 2 || w.Queued() != 1 {
+		t.Fatalf("running %d queued %d", w.Running(), w.Queued())
+	}
+	if _, err := w.Go(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expect queue full, got %v", err)
+	}
+	close(release)
+	for i, f := range futures {
+		if v, err := f.Get(ctx); err != nil || v != i*10 {
+			t.Fatalf("future %d: %v %v", i, v, err)
+		}
+	}
+
+	f, _ := w.Go(ctx, func(ctx context.Context) error { panic("boom") })
+	var pe *PanicError
+	if _, err := f.Get(ctx); !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("expect panic error, got %v", err)
+	}
+
+	// Shutdown 等待队列中的任务执行完
+	var done atomic.Int64
+	for i := 0; i < 3; i++ {
+		_, _ = w.Go(ctx, func(ctx context.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			done.Add(1)
+			return nil
+		})
+	}
+	if err := w.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if done.Load() != 3 {
+		t.Fatalf("shutdown before drain: %d", done.Load())
+	}
+	if _, err := w.Go(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrWorkersClosed) {
+		t.Fatalf("submit after shutdown: %v", err)
+	}
+}
+
+func TestWorkersReject(t *testing.T) {
+	ctx := context.Background()
+	block := make(chan struct{})
+	w := NewWorkers(WorkersOption{MaxWorkers: 1, Reject: RejectCallerRuns})
+	_, _ = w.Go(ctx, func(ctx context.Context) error { <-block; return nil })
+	ran := false
+	if _, err := w.Go(ctx, func(ctx context.Context) error { ran = true; return nil }); err != nil || !ran {
+		t.Fatalf("caller runs: %v %v", ran, err)
+	}
+
+	w2 := NewWorkers(WorkersOption{MaxWorkers: 1, Reject: RejectBlock})
+	_, _ = w2.Go(ctx, func(ctx context.Context) error { <-block; return nil })
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := w2.Go(timeout, func(ctx context.Context) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("block: %v", err)
+	}
+	close(block)
+	_ = w.Shutdown(ctx)
+	_ = w2.Shutdown(ctx)
+}
+
+func TestBreaker(t *testing.T) {
+	var changes []string
+	b := NewBreaker(BreakerOption{
+		MinRequests:    4,
+		FailureRate:    0.5,
+		Cooldown:       20 * time.Millisecond,
+		HalfOpenProbes: 2,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	fail := errors.New("fail")
+	for _, err := range []error{nil, fail, nil, fail} {
+		_ = b.Do(func() error { return err })
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expect open, got %v", b.State())
+	}
+	if err := b.Do(func() error { return nil }); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("expect rejected, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, _ := b.Allow()
+	if _, err := b.Allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("half-open should limit probes, got %v", err)
+	}
+	done1(nil)
+	done2(nil)
+	if b.State() != StateClosed {
+		t.Fatalf("expect closed, got %v", b.State())
+	}
+	want := "closed->open,open->half-open,half-open->closed"
+	if got := strings.Join(changes, ","); got != want {
+		t.Fatalf("changes = %s", got)
+	}
+}
+
+func TestBreakerPool(t *testing.T) {
+	down := errors.New("connection timeout")
+	var calls atomic.Int64
+	p := New(func(ctx context.Context) (*conn, error) {
+		calls.Add(1)
+		return nil, down
+	}, Options[*conn]{})
+	bp := WithBreaker[*conn](p, NewBreaker(BreakerOption{ConsecutiveFailures: 3, Cooldown: time.Hour}))
+	for i := 0; i < 10; i++ {
+		_, err := bp.Get(context.Background())
+		if i < 3 && !errors.Is(err, down) || i >= 3 && !errors.Is(err, ErrBreakerOpen) {
+			t.Fatalf("get %d: %v", i, err)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("factory called %d times", calls.Load())
+	}
+	if _, err := bp.Open(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("open: %v", err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	o := NewObjects(func() []int { return make([]int, 0, 8) }, func(s *[]int) { *s = (*s)[:0] })
+	s := o.Get()
+	*s = append(*s, 1, 2, 3)
+	o.Put(s)
+	s2 := o.Get()
+	if len(*s2) != 0 || cap(*s2) < 8 {
+		t.Fatalf("object not reset: %v", *s2)
+	}
+	if st := o.Stats(); st.Gets != 2 || st.Puts != 1 || st.Hits+st.Misses != 2 || st.Misses < 1 {
+		t.Fatalf("stats: %+v", st)
+	}
+
+	b := NewBytes(64, 256)
+	for _, size := range []int{1, 64, 65, 256} {
+		buf := b.Get(size)
+		if len(*buf) != 0 || cap(*buf) < size {
+			t.Fatalf("get(%d): len %d cap %d", size, len(*buf), cap(*buf))
+		}
+		b.Put(buf)
+	}
+	big := b.Get(1000)
+	if cap(*big) < 1000 {
+		t.Fatalf("big cap %d", cap(*big))
+	}
+	b.Put(big) // 超过最大分级,丢弃
+	small := make([]byte, 0, 100)
+	b.Put(&small) // 放入64分级
+	if got := b.Get(64); cap(*got) < 64 {
+		t.Fatalf("cap %d", cap(*got))
+	}
+	if st := b.Stats(); st.Gets != 6 || st.Misses < 1 {
+		t.Fatalf("bytes stats: %+v", st)
+	}
+}
@@ -0,0 +1,357 @@
+package wg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadConfig 读取并合并多个配置文件到T, 后面的文件覆盖前面的(map深度合并), 如 LoadConfig[Config]("config.yaml", "config.prod.yaml")
+//  1. 按扩展名解析 .yaml/.yml, .json, .toml
+//  2. 解析后替换字符串值中的 ${ENV} 和 ${ENV:default}, 环境变量的内容不会改变文件结构;
+//     替换结果按目标字段的类型解码, 如 port: ${PORT:8080} 得到整数, 字符串字段保持原文(如 1.10, 01234)
+//  3. 结构体字段的 default:"..." 标签作为默认值, 配置文件中出现的字段会覆盖默认值
+//  4. 按 validate:"required,min=1" 标签校验, 所有错误合并返回
+//
+// 所有格式都使用 yaml 标签匹配字段名
+func LoadConfig[T any](paths ...string) (T, error) {
+	var cfg T
+	if len(paths) == 0 {
+		return cfg, errors.New("LoadConfig: no config file")
+	}
+	merged := make(map[string]interface{})
+	for _, path := range paths {
+		m, err := readConfigFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		mergeMap(merged, m)
+	}
+	if err := decodeConfig(merged, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, Validate(cfg)
+}
+
+// decodeConfig 先填充默认值,再用合并后的配置覆盖
+func decodeConfig(merged map[string]interface{}, dst interface{}) error {
+	if err := SetDefaults(dst); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("LoadConfig: decode fail: %w", err)
+	}
+	return nil
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &result)
+	case ".json":
+		// UseNumber 避免超过 2^53 的整数转成float64后丢失精度
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&result)
+	case ".toml":
+		err = toml.Unmarshal(data, &result)
+	default:
+		return nil, fmt.Errorf("LoadConfig: unsupported config file type %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadConfig: parse %s fail: %w", path, err)
+	}
+	expandValue(result)
+	return result, nil
+}
+
+// expandValue 递归替换map和切片中字符串值的环境变量, 并将 json.Number 转换为数字
+func expandValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return expandScalar(val)
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = expandValue(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = expandValue(item)
+		}
+	}
+	return v
+}
+
+// envString 替换过环境变量的字符串; 编码成无tag的plain标量,由yaml按目标字段的类型解码,
+// 字符串字段得到原文, 数字和布尔字段得到对应的值
+type envString string
+
+func (e envString) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(e)}, nil
+}
+
+func expandScalar(s string) interface{} {
+	if !envPattern.MatchString(s) {
+		return s
+	}
+	return envString(ExpandEnv(s))
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:([^}]*))?\}`)
+
+// ExpandEnv 替换 ${ENV} 和 ${ENV:default}; 环境变量未设置时使用默认值,没有默认值时替换为空
+func ExpandEnv(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		sub := envPattern.FindStringSubmatch(match)
+		if val, ok := os.LookupEnv(sub[1]); ok {
+			return val
+		}
+		return sub[3]
+	})
+}
+
+// mergeMap 将src深度合并到dst, 两边都是map时递归合并, 否则src覆盖dst
+func mergeMap(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok1 := v.(map[string]interface{})
+		dm, ok2 := dst[k].(map[string]interface{})
+		if ok1 && ok2 {
+			mergeMap(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// SetDefaults 对零值字段设置 default 标签中的值, ptr 必须是结构体指针; 切片使用逗号分隔
+func SetDefaults(ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("SetDefaults: need a non-nil pointer")
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return setDefaults(rv, "")
+}
+
+func setDefaults(rv reflect.Value, path string) error {
+	var errs []error
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		name := joinConfigPath(path, configName(field))
+		if def, ok := field.Tag.Lookup("default"); ok && fv.IsZero() {
+			if err := setString(fv, def); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid default %q: %w", name, def, err))
+			}
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			if err := setDefaults(fv, name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setString 将字符串转换成字段的类型后赋值
+func setString(fv reflect.Value, s string) error {
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setString(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	case reflect.Ptr:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setString(elem.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// Validate 按 validate 标签校验结构体, 返回所有错误合并后的error, 每个错误形如 "server.port: min=1, got 0"
+//
+//	required    非零值
+//	min=N,max=N 数字比较数值, 字符串/切片/map比较长度
+//	oneof=a b c 取值必须是其中之一
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("Validate: nil value")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return errors.Join(validateStruct(rv, "")...)
+}
+
+func validateStruct(rv reflect.Value, path string) []error {
+	var errs []error
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		name := joinConfigPath(path, configName(field))
+		if tag := field.Tag.Get("validate"); tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				if err := validateRule(fv, strings.TrimSpace(rule)); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				}
+			}
+		}
+		inner := fv
+		if inner.Kind() == reflect.Ptr && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() == reflect.Struct && inner.Type() != reflect.TypeOf(time.Time{}) {
+			errs = append(errs, validateStruct(inner, name)...)
+		}
+	}
+	return errs
+}
+
+func validateRule(fv reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "":
+		return nil
+	case "required":
+		if fv.IsZero() {
+			return errors.New("required")
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q", rule)
+		}
+		val, ok := measure(fv)
+		if !ok {
+			return fmt.Errorf("rule %q not supported for %s", rule, fv.Type())
+		}
+		if name == "min" && val < limit || name == "max" && val > limit {
+			return fmt.Errorf("%s, got %v", rule, val)
+		}
+	case "oneof":
+		got := fmt.Sprint(fv.Interface())
+		for _, option := range strings.Fields(arg) {
+			if got == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s, got %q", rule, got)
+	default:
+		return fmt.Errorf("unknown rule %q", rule)
+	}
+	return nil
+}
+
+// measure 数字取数值, 字符串/切片/map取长度
+func measure(fv reflect.Value) (float64, bool) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true
+	}
+	return 0, false
+}
+
+// configName 与 yaml.v3 一致: 使用yaml标签,没有时为小写的字段名
+func configName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" && name != "-" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func joinConfigPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,226 @@
+package wg
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/wg00001/wgo-sdk/wg_log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConfigWatcher 定期检查配置文件的修改时间,大小和内容哈希,变化时重新执行 LoadConfig
+// 校验通过后原子地替换 Get 返回的配置并通知订阅者; 解析或校验失败时保留上一份配置
+//
+//	w, err := wg.WatchConfig[Config](5*time.Second, "config.yaml", "config.prod.yaml")
+//	w.Subscribe("limits.export", func(old, new interface{}) { log.Println("export limit:", old, "->", new) })
+//	limit := w.Get().Limits.Export
+type ConfigWatcher[T any] struct {
+	paths   []string
+	current atomic.Pointer[T]
+
+	mu      sync.Mutex // 保护 stamps, subs, 并串行化 Reload
+	stamps  map[string]fileStamp
+	subs    map[string]map[int]func(old, new interface{})
+	nextID  int
+	onError func(err error)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte // 修改时间和大小相同时比较内容,避免同样大小且在mtime精度内的修改被忽略
+}
+
+// WatchConfig 加载配置并每隔interval检查文件变化; 首次加载失败时返回错误
+func WatchConfig[T any](interval time.Duration, paths ...string) (*ConfigWatcher[T], error) {
+	w := &ConfigWatcher[T]{
+		paths:  paths,
+		stamps: make(map[string]fileStamp),
+		subs:   make(map[string]map[int]func(old, new interface{})),
+		onError: func(err error) {
+			wg_log.Warring("ConfigWatcher: reload rejected, keep last good config:", err)
+		},
+		stop: make(chan struct{}),
+	}
+	w.stamps = w.stat()
+	cfg, err := LoadConfig[T](paths...)
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(&cfg)
+	if interval > 0 {
+		go w.poll(interval)
+	}
+	return w, nil
+}
+
+// Get 当前生效的配置快照,调用方不应修改
+func (w *ConfigWatcher[T]) Get() *T {
+	return w.current.Load()
+}
+
+// OnError 设置重新加载失败时的回调,默认打印警告日志
+func (w *ConfigWatcher[T]) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
+// Subscribe 订阅配置中某个路径的变化, 路径为yaml字段名以点连接(如 "server.port"), 空字符串表示整个配置
+// 配置重新加载且该路径的值发生变化时调用fn; 返回取消订阅的函数
+func (w *ConfigWatcher[T]) Subscribe(keyPath string, fn func(old, new interface{})) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.subs[keyPath] == nil {
+		w.subs[keyPath] = make(map[int]func(old, new interface{}))
+	}
+	id := w.nextID
+	w.nextID++
+	w.subs[keyPath][id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs[keyPath], id)
+	}
+}
+
+// Reload 立即重新加载配置; 失败时保留当前配置并返回错误
+func (w *ConfigWatcher[T]) Reload() error {
+	w.mu.Lock()
+	w.stamps = w.stat()
+	cfg, err := LoadConfig[T](w.paths...)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	old := w.current.Swap(&cfg)
+	type change struct {
+		fns      []func(old, new interface{})
+		old, new interface{}
+	}
+	var changes []change
+	for path, subs := range w.subs {
+		if len(subs) == 0 {
+			continue
+		}
+		ov, _ := ConfigValue(old, path)
+		nv, _ := ConfigValue(&cfg, path)
+		if reflect.DeepEqual(ov, nv) {
+			continue
+		}
+		c := change{old: ov, new: nv}
+		for _, fn := range subs {
+			c.fns = append(c.fns, fn)
+		}
+		changes = append(changes, c)
+	}
+	w.mu.Unlock()
+	// 在锁外通知,订阅者中可以调用 Get 和 Subscribe
+	for _, c := range changes {
+		for _, fn := range c.fns {
+			fn(c.old, c.new)
+		}
+	}
+	return nil
+}
+
+// Close 停止检查文件变化
+func (w *ConfigWatcher[T]) Close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+func (w *ConfigWatcher[T]) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		if !w.changed() {
+			continue
+		}
+		if err := w.Reload(); err != nil {
+			w.mu.Lock()
+			onError := w.onError
+			w.mu.Unlock()
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (w *ConfigWatcher[T]) changed() bool {
+	stamps := w.stat()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !reflect.DeepEqual(stamps, w.stamps)
+}
+
+// stat 记录所有文件的修改时间,大小和内容哈希,不存在或读取失败的文件记为零值
+func (w *ConfigWatcher[T]) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(w.paths))
+	for _, path := range w.paths {
+		stamps[path] = fileStamp{}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(data)}
+	}
+	return stamps
+}
+
+// ConfigValue 按yaml字段名路径(如 "server.port")取出配置中的值,支持结构体,指针和key为字符串的map
+func ConfigValue(cfg interface{}, keyPath string) (interface{}, error) {
+	rv := reflect.ValueOf(cfg)
+	if keyPath == "" {
+		return cfg, nil
+	}
+	for _, name := range strings.Split(keyPath, ".") {
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, fmt.Errorf("ConfigValue: %s is nil", keyPath)
+			}
+			rv = rv.Elem()
+		}
+		switch rv.Kind() {
+		case reflect.Struct:
+			found := false
+			for i := 0; i < rv.NumField(); i++ {
+				field := rv.Type().Field(i)
+				if field.IsExported() && configName(field) == name {
+					rv, found = rv.Field(i), true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("ConfigValue: %s not found", keyPath)
+			}
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("ConfigValue: %s not found", keyPath)
+			}
+			rv = rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !rv.IsValid() {
+				return nil, fmt.Errorf("ConfigValue: %s not found", keyPath)
+			}
+		default:
+			return nil, fmt.Errorf("ConfigValue: %s not found", keyPath)
+		}
+	}
+	return rv.Interface(), nil
+}
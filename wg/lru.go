@@ -0,0 +1,111 @@
+package wg
+
+import (
+	"sync"
+)
+
+// LRU 容量固定的最近最少使用缓存,并发安全; 超出容量时淘汰最久未使用的元素
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	index    map[K]*node[K, V]
+	list     linkedList[K, V] // 头部为最近使用
+	onEvict  func(key K, value V)
+}
+
+// NewLRU capacity 最小为1; onEvict 在元素因容量被淘汰时调用(Remove和Purge不会调用),可以为nil
+func NewLRU[K comparable, V any](capacity int, onEvict func(key K, value V)) *LRU[K, V] {
+	l := &LRU[K, V]{
+		capacity: max(capacity, 1),
+		index:    make(map[K]*node[K, V]),
+		onEvict:  onEvict,
+	}
+	l.list.init()
+	return l
+}
+
+// Get 获取值并标记为最近使用
+func (l *LRU[K, V]) Get(key K) (value V, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, ok := l.index[key]
+	if !ok {
+		return value, false
+	}
+	l.list.moveToFront(n)
+	return n.value, true
+}
+
+// Peek 获取值,不改变使用顺序
+func (l *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, ok := l.index[key]
+	if !ok {
+		return value, false
+	}
+	return n.value, true
+}
+
+// Set 设置值并标记为最近使用,返回是否淘汰了元素; 淘汰回调在锁外调用
+func (l *LRU[K, V]) Set(key K, value V) (evicted bool) {
+	l.mu.Lock()
+	if n, ok := l.index[key]; ok {
+		n.value = value
+		l.list.moveToFront(n)
+		l.mu.Unlock()
+		return false
+	}
+	l.index[key] = l.list.pushFront(key, value)
+	var old *node[K, V]
+	if len(l.index) > l.capacity {
+		old = l.list.back()
+		l.list.remove(old)
+		delete(l.index, old.key)
+	}
+	l.mu.Unlock()
+	if old == nil {
+		return false
+	}
+	if l.onEvict != nil {
+		l.onEvict(old.key, old.value)
+	}
+	return true
+}
+
+// Remove 删除元素,返回是否存在
+func (l *LRU[K, V]) Remove(key K) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, ok := l.index[key]
+	if ok {
+		l.list.remove(n)
+		delete(l.index, key)
+	}
+	return ok
+}
+
+func (l *LRU[K, V]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.index)
+}
+
+// Keys 从最近使用到最久未使用的所有key
+func (l *LRU[K, V]) Keys() []K {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]K, 0, len(l.index))
+	for n := l.list.front(); n != nil; n = l.list.next(n) {
+		res = append(res, n.key)
+	}
+	return res
+}
+
+// Purge 清空缓存
+func (l *LRU[K, V]) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.index = make(map[K]*node[K, V])
+	l.list.init()
+}
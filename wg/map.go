@@ -22,56 +22,108 @@ func MapToValueSlice[K comparable, V any](table map[K]V) []V {
 
 // MapSliceToTable 将map数组转成表格,便于csv下载等. 需要传入title
 func MapSliceToTable[K comparable, V any](original []map[K]V, titles []K) [][]V {
-	n := len(original)
-	m := len(titles)
-	if n == 0 || m == 0 {
-		return [][]V{}
-	}
-	table := make([][]V, 0, len(original))
-	for _, mapKV := range original {
-		cur := make([]V, m)
-		for idx, title := range titles {
-			cur[idx] = mapKV[title]
-		}
-		table = append(table, cur)
-	}
-	return table
+	var placeholder V
+	return mapSliceToTable(original, titles, placeholder)
 }
 
-// MapSliceToTableDESC 将map数组转成表格,便于csv下载等. 自动降序排序title. 时间复杂度O(n+m+m⋅logm+n⋅m)=O(n⋅m+m⋅logm)
+// MapSliceToTableDESC 将map数组转成表格,便于csv下载等. title为所有行的key的并集,自动降序排序. 时间复杂度O(n⋅m+m⋅logm)
 func MapSliceToTableDESC[K Ordered, V any](original []map[K]V) ([]K, [][]V) {
-	if len(original) == 0 {
-		return []K{}, [][]V{}
-	}
-	titles := getAllTitle(original)
-	sort.Slice(titles, func(i, j int) bool {
-		return titles[i] > titles[j]
-	})
-	table := MapSliceToTable(original, titles)
-	return titles, table
+	return MapSliceToTableWith(original, TableOption[K, V]{Desc: true})
 }
 
-// MapSliceToTableASC 将map数组转成表格,便于csv下载等. 自动升序排序title. 时间复杂度O(n+m+m⋅logm+n⋅m)=O(n⋅m+m⋅logm)
+// MapSliceToTableASC 将map数组转成表格,便于csv下载等. title为所有行的key的并集,自动升序排序. 时间复杂度O(n⋅m+m⋅logm)
 func MapSliceToTableASC[K Ordered, V any](original []map[K]V) ([]K, [][]V) {
-	if len(original) == 0 {
+	return MapSliceToTableWith(original, TableOption[K, V]{})
+}
+
+// TableOption 表格的列配置
+type TableOption[K comparable, V any] struct {
+	Pin         []K     // 固定在最前面的列,按给定顺序输出,数据中没有的列也会输出
+	Exclude     []K     // 不输出的列
+	Rename      map[K]K // 返回的title中替换列名,取值仍使用原列名
+	Placeholder V       // 行中缺少该列时填充的值
+	Desc        bool    // 除固定列外的其余列降序排序,默认升序
+}
+
+// MapSliceToTableWith 按配置将map数组转成表格,返回title和表格内容
+func MapSliceToTableWith[K Ordered, V any](original []map[K]V, option TableOption[K, V]) ([]K, [][]V) {
+	if len(original) == 0 && len(option.Pin) == 0 {
 		return []K{}, [][]V{}
 	}
-	titles := getAllTitle(original)
-	sort.Slice(titles, func(i, j int) bool {
-		return titles[i] < titles[j]
+	columns := TableColumns(FromSlice(original), option)
+	return option.titles(columns), mapSliceToTable(original, columns, option.Placeholder)
+}
+
+// TableColumns 按配置返回所有行的列(key的并集): 先输出固定列,其余列排序; 结果是取值用的原列名,不经过 Rename
+func TableColumns[K Ordered, V any](rows Stream[map[K]V], option TableOption[K, V]) []K {
+	exclude := NewSet(option.Exclude...)
+	pinned := NewSet(option.Pin...)
+	rest := NewSet[K]()
+	rows.ForEach(func(row map[K]V) {
+		for k := range row {
+			if !exclude.Has(k) && !pinned.Has(k) {
+				rest.Add(k)
+			}
+		}
 	})
-	table := MapSliceToTable(original, titles)
-	return titles, table
+	sorted := SetToSortedSlice(rest)
+	if option.Desc {
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i] > sorted[j]
+		})
+	}
+	columns := make([]K, 0, len(option.Pin)+len(sorted))
+	for _, k := range option.Pin {
+		if !exclude.Has(k) {
+			columns = append(columns, k)
+		}
+	}
+	return append(columns, sorted...)
+}
+
+// MapStreamToTable 流式地将map转成表格行,适用于大量数据逐行写入csv; columns 可以由 TableColumns 得到
+//
+//	columns := wg.TableColumns(wg.FromSlice(rows), option)
+//	writer.Write(titles)
+//	wg.MapStreamToTable(wg.FromChan(rowChan), columns, "").ForEach(func(row []string) { writer.Write(row) })
+func MapStreamToTable[K comparable, V any](rows Stream[map[K]V], columns []K, placeholder V) Stream[[]V] {
+	return Map(rows, func(row map[K]V) []V {
+		return tableRow(row, columns, placeholder)
+	})
+}
+
+// titles 对列名应用 Rename
+func (o TableOption[K, V]) titles(columns []K) []K {
+	titles := make([]K, len(columns))
+	for i, k := range columns {
+		if name, ok := o.Rename[k]; ok {
+			titles[i] = name
+		} else {
+			titles[i] = k
+		}
+	}
+	return titles
+}
+
+func mapSliceToTable[K comparable, V any](original []map[K]V, titles []K, placeholder V) [][]V {
+	if len(original) == 0 || len(titles) == 0 {
+		return [][]V{}
+	}
+	table := make([][]V, 0, len(original))
+	for _, mapKV := range original {
+		table = append(table, tableRow(mapKV, titles, placeholder))
+	}
+	return table
 }
 
-func getAllTitle[K Ordered, V any](original []map[K]V) []K {
-	m, mIdx := 0, 0
-	//遍历找出元素最多的列,提取出完整title
-	for i, v := range original {
-		if len(v) > m {
-			m = len(v)
-			mIdx = i
+func tableRow[K comparable, V any](row map[K]V, titles []K, placeholder V) []V {
+	cur := make([]V, len(titles))
+	for idx, title := range titles {
+		if v, ok := row[title]; ok {
+			cur[idx] = v
+		} else {
+			cur[idx] = placeholder
 		}
 	}
-	return MapToKeySlice(original[mIdx])
+	return cur
 }
@@ -0,0 +1,267 @@
+package wg
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// OrderedMap 按插入顺序遍历的map,序列化成JSON时也保持插入顺序; 非并发安全
+// 用于需要稳定列顺序的场景,如表头,CSV导出
+// 与内置map一样是引用语义: 赋值或按值传递后的副本与原map共享数据; 零值在第一次 Set 前复制则互不影响
+type OrderedMap[K comparable, V any] struct {
+	index map[K]*node[K, V]
+	list  linkedList[K, V]
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	m := &OrderedMap[K, V]{index: make(map[K]*node[K, V])}
+	m.list.init()
+	return m
+}
+
+func (m *OrderedMap[K, V]) lazyInit() {
+	if m.index == nil {
+		m.index = make(map[K]*node[K, V])
+		m.list.init()
+	}
+}
+
+// Set 设置值,已存在的key保持原来的位置
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	m.lazyInit()
+	if n, ok := m.index[key]; ok {
+		n.value = value
+		return
+	}
+	m.index[key] = m.list.pushBack(key, value)
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	n, ok := m.index[key]
+	if !ok {
+		return value, false
+	}
+	return n.value, true
+}
+
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	_, ok := m.index[key]
+	return ok
+}
+
+func (m *OrderedMap[K, V]) Delete(key K) {
+	if n, ok := m.index[key]; ok {
+		m.list.remove(n)
+		delete(m.index, key)
+	}
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.index)
+}
+
+// Keys 按插入顺序返回所有key
+func (m *OrderedMap[K, V]) Keys() []K {
+	res := make([]K, 0, m.Len())
+	m.Range(func(key K, _ V) bool {
+		res = append(res, key)
+		return true
+	})
+	return res
+}
+
+// Values 按插入顺序返回所有value
+func (m *OrderedMap[K, V]) Values() []V {
+	res := make([]V, 0, m.Len())
+	m.Range(func(_ K, value V) bool {
+		res = append(res, value)
+		return true
+	})
+	return res
+}
+
+// Range 按插入顺序遍历, fn 返回false时停止
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	if m.index == nil {
+		return
+	}
+	for n := m.list.front(); n != nil; n = m.list.next(n) {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// Stream 按插入顺序遍历键值对
+func (m *OrderedMap[K, V]) Stream() Stream[Entry[K, V]] {
+	return FromSeq(func(yield func(Entry[K, V]) bool) {
+		m.Range(func(key K, value V) bool {
+			return yield(Entry[K, V]{Key: key, Value: value})
+		})
+	})
+}
+
+// MarshalJSON 序列化成JSON对象,字段按插入顺序; 非字符串的key使用 encoding.TextMarshaler 或 fmt.Sprint 转换
+func (m OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	var err error
+	first := true
+	m.Range(func(key K, value V) bool {
+		var k, v []byte
+		if k, err = marshalKey(key); err != nil {
+			return false
+		}
+		if v, err = json.Marshal(value); err != nil {
+			return false
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 从JSON对象反序列化,按字段在JSON中出现的顺序插入; 与内置map一致, null 不做任何修改
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("OrderedMap: expect JSON object, got %v", tok)
+	}
+	*m = OrderedMap[K, V]{}
+	m.lazyInit()
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		key, err := unmarshalKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+		var value V
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		m.Set(key, value)
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func marshalKey[K comparable](key K) ([]byte, error) {
+	if tm, ok := any(key).(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(string(text))
+	}
+	rv := reflect.ValueOf(key)
+	if rv.Kind() == reflect.String {
+		return json.Marshal(rv.String())
+	}
+	return json.Marshal(fmt.Sprint(key))
+}
+
+func unmarshalKey[K comparable](s string) (key K, err error) {
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		return key, tu.UnmarshalText([]byte(s))
+	}
+	rv := reflect.ValueOf(&key).Elem()
+	if rv.Kind() == reflect.String {
+		rv.SetString(s)
+		return key, nil
+	}
+	if err = json.Unmarshal([]byte(s), &key); err != nil {
+		return key, fmt.Errorf("OrderedMap: invalid key %q: %w", s, err)
+	}
+	return key, nil
+}
+
+// node 双向链表节点
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *node[K, V]
+}
+
+// linkedList 带哨兵的双向链表,OrderedMap 和 LRU 共用
+// 哨兵分配在堆上,复制 linkedList 后副本仍指向同一个链表,不会出现副本的哨兵与节点互相指错
+type linkedList[K comparable, V any] struct {
+	root *node[K, V]
+}
+
+func (l *linkedList[K, V]) init() {
+	l.root = &node[K, V]{}
+	l.root.prev, l.root.next = l.root, l.root
+}
+
+func (l *linkedList[K, V]) front() *node[K, V] {
+	return l.next(l.root)
+}
+
+func (l *linkedList[K, V]) back() *node[K, V] {
+	if l.root.prev == l.root {
+		return nil
+	}
+	return l.root.prev
+}
+
+func (l *linkedList[K, V]) next(n *node[K, V]) *node[K, V] {
+	if n.next == l.root {
+		return nil
+	}
+	return n.next
+}
+
+func (l *linkedList[K, V]) pushBack(key K, value V) *node[K, V] {
+	n := &node[K, V]{key: key, value: value}
+	l.insertAfter(n, l.root.prev)
+	return n
+}
+
+func (l *linkedList[K, V]) pushFront(key K, value V) *node[K, V] {
+	n := &node[K, V]{key: key, value: value}
+	l.insertAfter(n, l.root)
+	return n
+}
+
+func (l *linkedList[K, V]) insertAfter(n, at *node[K, V]) {
+	n.prev, n.next = at, at.next
+	at.next.prev = n
+	at.next = n
+}
+
+func (l *linkedList[K, V]) remove(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+}
+
+func (l *linkedList[K, V]) moveToFront(n *node[K, V]) {
+	if l.root.next == n {
+		return
+	}
+	l.remove(n)
+	l.insertAfter(n, l.root)
+}
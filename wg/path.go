@@ -0,0 +1,88 @@
+package wg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RootEnv 设置该环境变量后 ProjectRoot 直接使用其值, 用于容器等没有 go.mod 的部署环境
+const RootEnv = "WG_PROJECT_ROOT"
+
+var (
+	markersMu   sync.RWMutex
+	rootMarkers = []string{"go.mod", ".git"}
+)
+
+// SetRootMarkers 设置识别项目根目录的标记文件或目录, 按顺序查找, 默认为 go.mod, .git
+func SetRootMarkers(markers ...string) {
+	markersMu.Lock()
+	defer markersMu.Unlock()
+	rootMarkers = append([]string(nil), markers...)
+}
+
+// ProjectRoot 返回项目根目录的绝对路径
+//  1. 环境变量 WG_PROJECT_ROOT
+//  2. 从当前工作目录向上查找包含标记的目录, go test 时工作目录为包目录,同样可以找到
+//  3. 从可执行文件所在目录向上查找
+func ProjectRoot() (string, error) {
+	if root := os.Getenv(RootEnv); root != "" {
+		return filepath.Abs(root)
+	}
+	markersMu.RLock()
+	markers := rootMarkers
+	markersMu.RUnlock()
+
+	var starts []string
+	if dir, err := os.Getwd(); err == nil {
+		starts = append(starts, dir)
+	}
+	if exe, err := os.Executable(); err == nil {
+		if exe, err = filepath.EvalSymlinks(exe); err == nil {
+			starts = append(starts, filepath.Dir(exe))
+		}
+	}
+	for _, start := range starts {
+		if root, ok := findRoot(start, markers); ok {
+			return root, nil
+		}
+	}
+	return "", errors.New("ProjectRoot: no marker " + strings.Join(markers, ", ") + " found, set " + RootEnv)
+}
+
+// findRoot 从dir向上查找第一个包含任一标记的目录
+func findRoot(dir string, markers []string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// ResolvePath 将相对于项目根目录的路径转成绝对路径, / 和 \ 都可以作为分隔符; 绝对路径原样返回
+//
+//	path, err := wg.ResolvePath("testdata/config.yaml")
+func ResolvePath(rel string) (string, error) {
+	rel = filepath.FromSlash(strings.ReplaceAll(rel, "\\", "/"))
+	if filepath.IsAbs(rel) {
+		return filepath.Clean(rel), nil
+	}
+	root, err := ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, rel), nil
+}
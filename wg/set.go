@@ -0,0 +1,205 @@
+package wg
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Set 集合,底层与 SliceToSet 的返回值相同,可以直接转换: wg.Set[int64](wg.SliceToSet(...))
+type Set[T comparable] map[T]struct{}
+
+// NewSet 使用元素创建集合
+func NewSet[T comparable](items ...T) Set[T] {
+	s := make(Set[T], len(items))
+	s.Add(items...)
+	return s
+}
+
+func (s Set[T]) Add(items ...T) {
+	for _, item := range items {
+		s[item] = struct{}{}
+	}
+}
+
+func (s Set[T]) Remove(items ...T) {
+	for _, item := range items {
+		delete(s, item)
+	}
+}
+
+func (s Set[T]) Has(item T) bool {
+	_, ok := s[item]
+	return ok
+}
+
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+func (s Set[T]) Clone() Set[T] {
+	res := make(Set[T], len(s))
+	for item := range s {
+		res[item] = struct{}{}
+	}
+	return res
+}
+
+// Slice 转成slice,顺序不固定; Ordered 类型可以使用 SetToSortedSlice
+func (s Set[T]) Slice() []T {
+	res := make([]T, 0, len(s))
+	for item := range s {
+		res = append(res, item)
+	}
+	return res
+}
+
+// Union 并集,返回新集合
+func (s Set[T]) Union(o Set[T]) Set[T] {
+	res := make(Set[T], max(len(s), len(o)))
+	for item := range s {
+		res[item] = struct{}{}
+	}
+	for item := range o {
+		res[item] = struct{}{}
+	}
+	return res
+}
+
+// Intersect 交集,返回新集合
+func (s Set[T]) Intersect(o Set[T]) Set[T] {
+	small, big := s, o
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+	res := make(Set[T], len(small))
+	for item := range small {
+		if big.Has(item) {
+			res[item] = struct{}{}
+		}
+	}
+	return res
+}
+
+// Difference 差集,在s中但不在o中的元素
+func (s Set[T]) Difference(o Set[T]) Set[T] {
+	res := make(Set[T], len(s))
+	for item := range s {
+		if !o.Has(item) {
+			res[item] = struct{}{}
+		}
+	}
+	return res
+}
+
+// SymmetricDifference 对称差集,只在其中一个集合中的元素
+func (s Set[T]) SymmetricDifference(o Set[T]) Set[T] {
+	res := s.Difference(o)
+	for item := range o {
+		if !s.Has(item) {
+			res[item] = struct{}{}
+		}
+	}
+	return res
+}
+
+// IsSubset s 的所有元素都在 o 中
+func (s Set[T]) IsSubset(o Set[T]) bool {
+	if len(s) > len(o) {
+		return false
+	}
+	for item := range s {
+		if !o.Has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset o 的所有元素都在 s 中
+func (s Set[T]) IsSuperset(o Set[T]) bool {
+	return o.IsSubset(s)
+}
+
+// Equal 两个集合元素相同
+func (s Set[T]) Equal(o Set[T]) bool {
+	return len(s) == len(o) && s.IsSubset(o)
+}
+
+// SetToSortedSlice 转成升序的slice
+func SetToSortedSlice[T Ordered](s Set[T]) []T {
+	res := s.Slice()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
+
+// MarshalJSON 序列化成JSON数组; 数字和字符串按升序,其他类型按序列化结果排序,保证输出稳定
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+	items := s.Slice()
+	encoded := make([][]byte, len(items))
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = b
+	}
+	sort.Sort(jsonItems[T]{values: items, encoded: encoded, less: lessFunc(items)})
+	return append(append([]byte("["), bytes.Join(encoded, []byte(","))...), ']'), nil
+}
+
+// UnmarshalJSON 从JSON数组反序列化,重复元素会被合并
+func (s *Set[T]) UnmarshalJSON(data []byte) error {
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*s = NewSet(items...)
+	return nil
+}
+
+type jsonItems[T any] struct {
+	values  []T
+	encoded [][]byte
+	less    func(i, j int) bool
+}
+
+func (j jsonItems[T]) Len() int { return len(j.values) }
+
+func (j jsonItems[T]) Less(a, b int) bool {
+	if j.less != nil {
+		return j.less(a, b)
+	}
+	return bytes.Compare(j.encoded[a], j.encoded[b]) < 0
+}
+
+func (j jsonItems[T]) Swap(a, b int) {
+	j.values[a], j.values[b] = j.values[b], j.values[a]
+	j.encoded[a], j.encoded[b] = j.encoded[b], j.encoded[a]
+}
+
+// lessFunc 数字和字符串类型的比较函数,其他类型返回nil
+func lessFunc[T any](items []T) func(i, j int) bool {
+	switch reflect.TypeOf((*T)(nil)).Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(i, j int) bool {
+			return reflect.ValueOf(items[i]).Int() < reflect.ValueOf(items[j]).Int()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(i, j int) bool {
+			return reflect.ValueOf(items[i]).Uint() < reflect.ValueOf(items[j]).Uint()
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(i, j int) bool {
+			return reflect.ValueOf(items[i]).Float() < reflect.ValueOf(items[j]).Float()
+		}
+	case reflect.String:
+		return func(i, j int) bool {
+			return reflect.ValueOf(items[i]).String() < reflect.ValueOf(items[j]).String()
+		}
+	}
+	return nil
+}
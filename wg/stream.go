@@ -0,0 +1,317 @@
+package wg
+
+import (
+	"github.com/wg00001/wgo-sdk/mr"
+)
+
+// Seq 惰性序列: 依次把元素交给yield, yield 返回false时停止; 与 Go 1.23 的 iter.Seq 形式相同
+type Seq[T any] func(yield func(item T) bool)
+
+// Entry map 的键值对
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Stream 惰性的链式处理, 只有调用 Collect, ForEach, Reduce 等终结操作时才会逐个处理元素,中间不生成slice
+//
+//	names := wg.Map(wg.FromSlice(users).Filter(isActive), getName).Take(10).Collect()
+type Stream[T any] struct {
+	seq     Seq[T]
+	workers int
+}
+
+// FromSeq 使用Seq创建Stream
+func FromSeq[T any](seq Seq[T]) Stream[T] {
+	return Stream[T]{seq: seq}
+}
+
+// Of 使用元素创建Stream
+func Of[T any](items ...T) Stream[T] {
+	return FromSlice(items)
+}
+
+func FromSlice[T any](slice []T) Stream[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for _, item := range slice {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// FromChan 从channel读取直到关闭; 提前停止时channel中剩余的元素不会被读取
+func FromChan[T any](ch <-chan T) Stream[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for item := range ch {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// FromMap 遍历map的键值对,顺序不固定
+func FromMap[K comparable, V any](m map[K]V) Stream[Entry[K, V]] {
+	return FromSeq(func(yield func(Entry[K, V]) bool) {
+		for k, v := range m {
+			if !yield(Entry[K, V]{Key: k, Value: v}) {
+				return
+			}
+		}
+	})
+}
+
+// Generate 使用生成函数创建Stream, next 返回false时结束
+func Generate[T any](next func() (T, bool)) Stream[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for {
+			item, ok := next()
+			if !ok || !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// Seq 返回底层的Seq
+func (s Stream[T]) Seq() Seq[T] {
+	if s.seq == nil {
+		return func(func(T) bool) {}
+	}
+	return s.seq
+}
+
+// Parallel 之后的 Map 和 Filter 使用 mr 以n个goroutine并发执行; 并发阶段会读完上游的所有元素,且不保证顺序
+func (s Stream[T]) Parallel(n int) Stream[T] {
+	s.workers = max(n, 1)
+	return s
+}
+
+// Sequential 取消 Parallel
+func (s Stream[T]) Sequential() Stream[T] {
+	s.workers = 0
+	return s
+}
+
+func (s Stream[T]) with(seq Seq[T]) Stream[T] {
+	return Stream[T]{seq: seq, workers: s.workers}
+}
+
+// Filter 保留fn返回true的元素
+func (s Stream[T]) Filter(fn func(item T) bool) Stream[T] {
+	if s.workers > 0 {
+		return parallel(s, s.workers, func(item T, write func(T)) {
+			if fn(item) {
+				write(item)
+			}
+		})
+	}
+	seq := s.Seq()
+	return s.with(func(yield func(T) bool) {
+		seq(func(item T) bool {
+			return !fn(item) || yield(item)
+		})
+	})
+}
+
+// Take 只取前n个元素,取够后上游停止
+func (s Stream[T]) Take(n int) Stream[T] {
+	seq := s.Seq()
+	return s.with(func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		count := 0
+		seq(func(item T) bool {
+			count++
+			return yield(item) && count < n
+		})
+	})
+}
+
+// Skip 跳过前n个元素
+func (s Stream[T]) Skip(n int) Stream[T] {
+	seq := s.Seq()
+	return s.with(func(yield func(T) bool) {
+		count := 0
+		seq(func(item T) bool {
+			if count < n {
+				count++
+				return true
+			}
+			return yield(item)
+		})
+	})
+}
+
+// Peek 元素经过时调用fn,用于调试
+func (s Stream[T]) Peek(fn func(item T)) Stream[T] {
+	seq := s.Seq()
+	return s.with(func(yield func(T) bool) {
+		seq(func(item T) bool {
+			fn(item)
+			return yield(item)
+		})
+	})
+}
+
+// ForEach 终结操作,依次处理每个元素
+func (s Stream[T]) ForEach(fn func(item T)) {
+	s.Seq()(func(item T) bool {
+		fn(item)
+		return true
+	})
+}
+
+// Collect 终结操作,收集成slice
+func (s Stream[T]) Collect() []T {
+	var res []T
+	s.Seq()(func(item T) bool {
+		res = append(res, item)
+		return true
+	})
+	return res
+}
+
+// Count 终结操作,元素个数
+func (s Stream[T]) Count() int {
+	n := 0
+	s.Seq()(func(T) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// First 终结操作,第一个元素
+func (s Stream[T]) First() (first T, ok bool) {
+	s.Seq()(func(item T) bool {
+		first, ok = item, true
+		return false
+	})
+	return first, ok
+}
+
+// Map 转换每个元素
+func Map[T, U any](s Stream[T], fn func(item T) U) Stream[U] {
+	if s.workers > 0 {
+		return parallel(s, s.workers, func(item T, write func(U)) {
+			write(fn(item))
+		})
+	}
+	seq := s.Seq()
+	return Stream[U]{workers: s.workers, seq: func(yield func(U) bool) {
+		seq(func(item T) bool {
+			return yield(fn(item))
+		})
+	}}
+}
+
+// FlatMap 每个元素转换成多个元素后展开
+func FlatMap[T, U any](s Stream[T], fn func(item T) []U) Stream[U] {
+	seq := s.Seq()
+	return Stream[U]{workers: s.workers, seq: func(yield func(U) bool) {
+		seq(func(item T) bool {
+			for _, u := range fn(item) {
+				if !yield(u) {
+					return false
+				}
+			}
+			return true
+		})
+	}}
+}
+
+// Distinct 去重,保留第一次出现的元素
+func Distinct[T comparable](s Stream[T]) Stream[T] {
+	seq := s.Seq()
+	return s.with(func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		seq(func(item T) bool {
+			if _, ok := seen[item]; ok {
+				return true
+			}
+			seen[item] = struct{}{}
+			return yield(item)
+		})
+	})
+}
+
+// Chunk 每size个元素分为一组,最后一组可能不足size
+func Chunk[T any](s Stream[T], size int) Stream[[]T] {
+	seq := s.Seq()
+	size = max(size, 1)
+	return Stream[[]T]{workers: s.workers, seq: func(yield func([]T) bool) {
+		cur := make([]T, 0, size)
+		stopped := false
+		seq(func(item T) bool {
+			cur = append(cur, item)
+			if len(cur) < size {
+				return true
+			}
+			chunk := cur
+			cur = make([]T, 0, size)
+			stopped = !yield(chunk)
+			return !stopped
+		})
+		if !stopped && len(cur) > 0 {
+			yield(cur)
+		}
+	}}
+}
+
+// Reduce 终结操作,从init开始依次累积
+func Reduce[T, U any](s Stream[T], init U, fn func(acc U, item T) U) U {
+	s.Seq()(func(item T) bool {
+		init = fn(init, item)
+		return true
+	})
+	return init
+}
+
+// GroupBy 终结操作,按key分组,组内保持原顺序
+func GroupBy[T any, K comparable](s Stream[T], key func(item T) K) map[K][]T {
+	res := make(map[K][]T)
+	s.Seq()(func(item T) bool {
+		k := key(item)
+		res[k] = append(res[k], item)
+		return true
+	})
+	return res
+}
+
+// parallel 使用 mr 并发处理上游的所有元素,处理结果在全部完成后依次输出; mapper 中的panic会在调用方重新抛出
+func parallel[T, U any](s Stream[T], workers int, fn func(item T, write func(U))) Stream[U] {
+	seq := s.Seq()
+	return Stream[U]{workers: workers, seq: func(yield func(U) bool) {
+		res, err := mr.New[T, U, []U]().
+			Generate(func(source chan<- T) {
+				seq(func(item T) bool {
+					source <- item
+					return true
+				})
+			}).
+			Mapper(func(item T, writer mr.Writer[U], cancel func(error)) {
+				fn(item, writer.Write)
+			}).
+			Reducer(func(pipe <-chan U, writer mr.Writer[[]U], cancel func(error)) {
+				var res []U
+				for u := range pipe {
+					res = append(res, u)
+				}
+				writer.Write(res)
+			}).
+			WithWorkers(workers).
+			Run()
+		if err != nil {
+			panic(err)
+		}
+		for _, u := range res {
+			if !yield(u) {
+				return
+			}
+		}
+	}}
+}
@@ -22,6 +22,9 @@ func ReadYAMLToMap(filePath string) (map[string]interface{}, error) {
 // GetRelativePath
 // @param: 来自内容根的路径
 // @return: 目标文件相对于当前启动目录的位置
+//
+// Deprecated: 以路径的第一段作为项目目录名在当前目录中查找,目录名重复或不在项目内启动时结果不正确;
+// 使用 ResolvePath 按标记文件(go.mod, .git 等)定位项目根目录并返回绝对路径
 func GetRelativePath(pathDir string) string {
 	dir, _ := os.Getwd()
 	if len(pathDir) == 0 {
@@ -1,11 +1,328 @@
 package wg
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSlice(t *testing.T) {
 	chunks := SliceChunk([]int{1, 2, 3, 4, 5, 6, 7}, 3)
 	fmt.Println(chunks) // 输出: [[1 2 3] [4 5 6] [7]]
 }
+
+func TestSet(t *testing.T) {
+	a := NewSet(1, 2, 3, 4)
+	b := Set[int](SliceToSet([]int{3, 4, 5}, func(item int) int { return item }))
+	check := func(name string, s Set[int], want ...int) {
+		t.Helper()
+		if !s.Equal(NewSet(want...)) {
+			t.Fatalf("%s = %v, want %v", name, SetToSortedSlice(s), want)
+		}
+	}
+	check("union", a.Union(b), 1, 2, 3, 4, 5)
+	check("intersect", a.Intersect(b), 3, 4)
+	check("difference", a.Difference(b), 1, 2)
+	check("symmetric", a.SymmetricDifference(b), 1, 2, 5)
+	if !NewSet(3, 4).IsSubset(a) || b.IsSubset(a) || !a.IsSuperset(NewSet(1)) {
+		t.Fatal("subset")
+	}
+	a.Remove(1)
+	a.Add(10)
+	if a.Has(1) || !a.Has(10) || a.Len() != 4 {
+		t.Fatalf("add/remove: %v", a)
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil || string(data) != "[2,3,4,10]" {
+		t.Fatalf("marshal: %s %v", data, err)
+	}
+	var s Set[int]
+	if err := json.Unmarshal([]byte("[5,5,6]"), &s); err != nil {
+		t.Fatal(err)
+	}
+	check("unmarshal", s, 5, 6)
+	if data, _ := json.Marshal(NewSet[any]("b", "a")); string(data) != `["a","b"]` {
+		t.Fatalf("marshal any: %s", data)
+	}
+}
+
+func TestStream(t *testing.T) {
+	pulled := 0
+	s := FromSlice([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}).Peek(func(int) { pulled++ })
+	got := Map(s.Filter(func(i int) bool { return i%2 == 0 }), func(i int) string {
+		return fmt.Sprint(i * 10)
+	}).Skip(1).Take(2).Collect()
+	if fmt.Sprint(got) != "[40 60]" || pulled != 6 {
+		t.Fatalf("got %v, pulled %d", got, pulled)
+	}
+
+	n := 0
+	gen := Generate(func() (int, bool) { n++; return n, true }) // 无限序列
+	if got := Chunk(Distinct(FlatMap(gen, func(i int) []int { return []int{i, i} })), 3).Take(2).Collect(); fmt.Sprint(got) != "[[1 2 3] [4 5 6]]" {
+		t.Fatalf("chunks %v", got)
+	}
+	if sum := Reduce(Of(1, 2, 3), 0, func(acc, i int) int { return acc + i }); sum != 6 {
+		t.Fatalf("sum %d", sum)
+	}
+	groups := GroupBy(Of("apple", "avocado", "banana"), func(s string) byte { return s[0] })
+	if len(groups['a']) != 2 || len(groups['b']) != 1 {
+		t.Fatalf("groups %v", groups)
+	}
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	close(ch)
+	if FromChan(ch).Count() != 2 || FromMap(map[string]int{"a": 1}).Count() != 1 {
+		t.Fatal("count")
+	}
+
+	squares := Map(FromSlice([]int{1, 2, 3, 4}).Parallel(4).Filter(func(i int) bool { return i > 1 }), func(i int) int { return i * i }).Collect()
+	if Reduce(FromSlice(squares), 0, func(acc, i int) int { return acc + i }) != 29 || len(squares) != 3 {
+		t.Fatalf("parallel %v", squares)
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	for i, k := range []string{"z", "a", "m"} {
+		m.Set(k, i)
+	}
+	m.Set("z", 10) // 已存在的key保持位置
+	m.Delete("a")
+	m.Set("b", 3)
+	data, err := json.Marshal(m)
+	if err != nil || string(data) != `{"z":10,"m":2,"b":3}` {
+		t.Fatalf("marshal: %s %v", data, err)
+	}
+	var back OrderedMap[string, int]
+	if err := json.Unmarshal([]byte(`{"q":1,"c":2,"x":3}`), &back); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(back.Keys(), back.Values()) != "[q c x] [1 2 3]" {
+		t.Fatalf("unmarshal: %v %v", back.Keys(), back.Values())
+	}
+	var ints OrderedMap[int, string]
+	ints.Set(2, "b")
+	ints.Set(1, "a")
+	intData, _ := json.Marshal(ints)
+	if string(intData) != `{"2":"b","1":"a"}` {
+		t.Fatalf("int keys: %s", intData)
+	}
+	if err := json.Unmarshal(intData, &ints); err != nil || fmt.Sprint(ints.Keys()) != "[2 1]" {
+		t.Fatalf("int keys: %v %v", ints.Keys(), err)
+	}
+
+	// 按值嵌入结构体, 以及复制后与原map共享数据
+	type wrapper struct {
+		M OrderedMap[string, int]
+	}
+	var w wrapper
+	w.M.Set("b", 1)
+	w.M.Set("a", 2)
+	copied := w
+	copied.M.Set("c", 3)
+	if data, err = json.Marshal(w); err != nil || string(data) != `{"M":{"b":1,"a":2,"c":3}}` {
+		t.Fatalf("embedded by value: %s %v", data, err)
+	}
+	if fmt.Sprint(copied.M.Keys()) != "[b a c]" {
+		t.Fatalf("copy: %v", copied.M.Keys())
+	}
+	if err = json.Unmarshal([]byte(`{"M":null}`), &w); err != nil || w.M.Len() != 3 {
+		t.Fatalf("null: %v %v", w.M.Keys(), err)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+	l := NewLRU(2, func(key string, value int) { evicted = append(evicted, key) })
+	l.Set("a", 1)
+	l.Set("b", 2)
+	l.Get("a")
+	if !l.Set("c", 3) || fmt.Sprint(evicted) != "[b]" {
+		t.Fatalf("evicted %v", evicted)
+	}
+	if _, ok := l.Peek("b"); ok || fmt.Sprint(l.Keys()) != "[c a]" {
+		t.Fatalf("keys %v", l.Keys())
+	}
+	l.Remove("c")
+	if l.Len() != 1 {
+		t.Fatalf("len %d", l.Len())
+	}
+}
+
+func TestMapSliceToTable(t *testing.T) {
+	rows := []map[string]string{
+		{"name": "a", "amount": "1", "city": "x"},
+		{"name": "b", "region": "north"}, // 窄行中才有的列
+	}
+	titles, table := MapSliceToTableASC(rows)
+	if fmt.Sprint(titles) != "[amount city name region]" || fmt.Sprint(table[1]) != "[  b north]" {
+		t.Fatalf("asc: %v %q", titles, table)
+	}
+	titles, table = MapSliceToTableWith(rows, TableOption[string, string]{
+		Pin:         []string{"name", "id"},
+		Exclude:     []string{"city"},
+		Rename:      map[string]string{"name": "姓名"},
+		Placeholder: "-",
+		Desc:        true,
+	})
+	if fmt.Sprint(titles) != "[姓名 id region amount]" || fmt.Sprint(table) != "[[a - - 1] [b - north -]]" {
+		t.Fatalf("with option: %v %v", titles, table)
+	}
+	columns := TableColumns(FromSlice(rows), TableOption[string, string]{Pin: []string{"name"}})
+	streamed := MapStreamToTable(FromSlice(rows), columns, "-").Collect()
+	if fmt.Sprint(columns, streamed) != "[name amount city region] [[a 1 x -] [b - - north]]" {
+		t.Fatalf("stream: %v %v", columns, streamed)
+	}
+}
+
+type testConfig struct {
+	Name    string `yaml:"name" validate:"required"`
+	ID      int64  `yaml:"id"`
+	Version string `yaml:"version"`
+	Zip     string `yaml:"zip"`
+	Server  struct {
+		Host    string        `yaml:"host" default:"127.0.0.1"`
+		Port    int           `yaml:"port" default:"8080" validate:"min=1,max=65535"`
+		Timeout time.Duration `yaml:"timeout" default:"5s"`
+	} `yaml:"server"`
+	Level  string   `yaml:"level" default:"info" validate:"oneof=debug info warn"`
+	Tags   []string `yaml:"tags" default:"a,b"`
+	Limits struct {
+		Export int `yaml:"export" validate:"min=1"`
+	} `yaml:"limits"`
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WG_TEST_PORT", "9090")
+	t.Setenv("WG_TEST_NAME", "svc # x\nlevel: trace")
+	t.Setenv("WG_TEST_VERSION", "1.10")
+	t.Setenv("WG_TEST_ZIP", "01234")
+	base := writeFile(t, dir, "base.yaml", "name: ${WG_TEST_NAME}\nversion: ${WG_TEST_VERSION}\nzip: ${WG_TEST_ZIP}\nserver:\n  host: ${WG_TEST_HOST:0.0.0.0}\n  timeout: 10s\nlimits:\n  export: 100\n")
+	prod := writeFile(t, dir, "prod.json", `{"id": 9007199254740993, "server": {"port": "${WG_TEST_PORT}"}, "level": "warn"}`)
+	extra := writeFile(t, dir, "extra.toml", "tags = [\"x\"]\n")
+
+	cfg, err := LoadConfig[testConfig](base, prod, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.Server
+	if s.Host != "0.0.0.0" || s.Port != 9090 || s.Timeout != 10*time.Second || cfg.Level != "warn" || fmt.Sprint(cfg.Tags) != "[x]" {
+		t.Fatalf("config: %+v", cfg)
+	}
+	// 环境变量中的 # 和换行不会改变文件结构; JSON 大整数不丢失精度
+	if cfg.Name != "svc # x\nlevel: trace" || cfg.ID != 9007199254740993 {
+		t.Fatalf("config: %q %d", cfg.Name, cfg.ID)
+	}
+	// 数字形式的环境变量赋给字符串字段时保持原文
+	if cfg.Version != "1.10" || cfg.Zip != "01234" {
+		t.Fatalf("config: %q %q", cfg.Version, cfg.Zip)
+	}
+
+	cfg, err = LoadConfig[testConfig](writeFile(t, dir, "defaults.yaml", "name: svc\nlimits: {export: 1}\n"))
+	if err != nil || cfg.Server.Port != 8080 || cfg.Server.Timeout != 5*time.Second || fmt.Sprint(cfg.Tags) != "[a b]" {
+		t.Fatalf("defaults: %+v %v", cfg, err)
+	}
+
+	bad := writeFile(t, dir, "bad.yaml", "server: {port: 70000}\nlevel: trace\n")
+	_, err = LoadConfig[testConfig](bad)
+	for _, want := range []string{"name: required", "server.port: max=65535", "level: oneof", "limits.export: min=1"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expect %q in %v", want, err)
+		}
+	}
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", "name: svc\nlimits: {export: 10}\n")
+	w, err := WatchConfig[testConfig](10*time.Millisecond, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	changes := make(chan string, 4)
+	w.Subscribe("limits.export", func(old, new interface{}) {
+		changes <- fmt.Sprint(old, "->", new)
+	})
+	w.Subscribe("name", func(old, new interface{}) {
+		changes <- "name changed"
+	})
+	errs := make(chan error, 4)
+	w.OnError(func(err error) { errs <- err })
+
+	// 校验失败的修改被拒绝
+	writeFile(t, dir, "config.yaml", "name: svc\nlimits: {export: -1}\n")
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "limits.export") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalid config not rejected")
+	}
+	if w.Get().Limits.Export != 10 {
+		t.Fatalf("last good config lost: %+v", w.Get())
+	}
+
+	// 与上一次大小相同的修改
+	writeFile(t, dir, "config.yaml", "name: svc\nlimits: {export: 20}\n")
+	select {
+	case c := <-changes:
+		if c != "10->20" {
+			t.Fatalf("change %s", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not delivered")
+	}
+	if w.Get().Limits.Export != 20 || len(changes) != 0 {
+		t.Fatalf("after reload: %+v, pending %d", w.Get(), len(changes))
+	}
+	if v, err := ConfigValue(w.Get(), "server.port"); err != nil || v != 8080 {
+		t.Fatalf("ConfigValue: %v %v", v, err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	root, err := ProjectRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatalf("root %s has no go.mod", root)
+	}
+	path, err := ResolvePath(`wg\yaml.go`)
+	if err != nil || path != filepath.Join(root, "wg", "yaml.go") {
+		t.Fatalf("resolve: %s %v", path, err)
+	}
+
+	dir := t.TempDir()
+	t.Setenv(RootEnv, dir)
+	if path, _ := ResolvePath("config/app.yaml"); path != filepath.Join(dir, "config", "app.yaml") {
+		t.Fatalf("env override: %s", path)
+	}
+	t.Setenv(RootEnv, "")
+	SetRootMarkers(".wgroot")
+	defer SetRootMarkers("go.mod", ".git")
+	nested := filepath.Join(dir, "a", "b")
+	_ = os.MkdirAll(nested, 0o755)
+	writeFile(t, dir, ".wgroot", "")
+	if got, ok := findRoot(nested, []string{".wgroot"}); !ok || got != dir {
+		t.Fatalf("marker: %s %v", got, ok)
+	}
+}
@@ -1,10 +1,14 @@
 package wg_csv
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/wg00001/wgo-sdk/debug"
+	"github.com/wg00001/wgo-sdk/pool"
 	"os"
 	"path/filepath"
 	"sync"
@@ -17,9 +21,33 @@ import (
 // If an error occurs, the error during sync is returned immediately, and the error after async is written to the csv file.
 var (
 	download = make(map[string]string)
-	mu       sync.Mutex
+	// running holds the future of the in-flight export per key, so repeated polls wait on it instead of resubmitting.
+	// A nil entry means another caller is submitting the job right now.
+	running = make(map[string]*pool.Future[struct{}])
+	mu      sync.Mutex
+
+	// Workers runs the csv writer functions, bounding the number of concurrent exports.
+	// When the queue is full AsyncWriteCSV returns pool.ErrQueueFull; replace it to tune the limits.
+	Workers = pool.NewWorkers(pool.WorkersOption{MaxWorkers: 4, QueueSize: 64, Reject: pool.RejectError})
 )
 
+func init() {
+	// report pending and finished exports to the debug introspection endpoint
+	debug.Register("wg_csv", func() interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		pending, ready := 0, make([]string, 0, len(download))
+		for _, file := range download {
+			if file == "" {
+				pending++
+			} else {
+				ready = append(ready, file)
+			}
+		}
+		return map[string]interface{}{"pending": pending, "ready": ready, "workers": Workers.Running(), "queued": Workers.Queued()}
+	})
+}
+
 // AsyncCSV generates a CSV file using a key derived from the MD5 hash of the provided request.
 // It calls AsyncWriteCSV with the hashed key to manage file creation and writing.
 func AsyncCSV(req interface{}, filePrefix string, writerFunc func(*csv.Writer) (err error)) (filename string, err error) {
@@ -41,48 +69,82 @@ func AsyncWriteCSV(key string, filePrefix string, writerFunc func(*csv.Writer) (
 		return p, nil
 	}
 	//检查CSV文件是否正在组装或已经组装完毕
-	finish := make(chan struct{})
-	errChan := make(chan error)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				err = fmt.Errorf("writerfunc Panic: %v", r)
-			}
-		}()
-		defer close(errChan)
-		defer close(finish)
-		fileName := fmt.Sprintf("%s_%s.csv", filePrefix, time.Now().Format("20060102_150405"))
-		filePath := filepath.Join("/app/cp/", fileName)
-		file, err := os.Create(filePath)
+	mu.Lock()
+	future, started := running[key]
+	if !started {
+		running[key] = nil
+	}
+	mu.Unlock()
+	if !started {
+		future, err = Workers.Go(context.Background(), func(ctx context.Context) error {
+			return writeCSV(key, filePrefix, writerFunc)
+		})
+		mu.Lock()
 		if err != nil {
-			errChan <- err
-			return
+			delete(running, key)
+			delete(download, key)
+			mu.Unlock()
+			return "", err
 		}
-		defer file.Close()
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
-
-		err = writerFunc(writer)
-		if err != nil {
-			writer.Write([]string{"err:", err.Error()})
-			errChan <- err
-			return
+		// the job may already have finished and removed its entry
+		if _, ok := running[key]; ok {
+			running[key] = future
 		}
-		mu.Lock()
-		download[key] = fileName //确认文件已经完成
 		mu.Unlock()
-		finish <- struct{}{}
-	}()
-	select {
-	//阻塞两秒
-	case <-time.After(time.Second * 2):
+	}
+	if future == nil {
 		return "", nil
-	case err = <-errChan:
-		delete(download, key)
+	}
+	//阻塞两秒
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	if _, err = future.Get(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "", nil
+		}
 		return "", err
-	case <-finish:
-		return GetFilepath(key), nil
 	}
+	return GetFilepath(key), nil
+}
+
+// writeCSV runs on Workers. It always clears the in-flight future of key, and clears the key itself
+// when the export fails (including panics), so the next poll starts a new export instead of waiting forever.
+func writeCSV(key string, filePrefix string, writerFunc func(*csv.Writer) (err error)) error {
+	ok := false
+	defer func() {
+		mu.Lock()
+		delete(running, key)
+		mu.Unlock()
+		if !ok {
+			removeKey(key)
+		}
+	}()
+	fileName := fmt.Sprintf("%s_%s.csv", filePrefix, time.Now().Format("20060102_150405"))
+	filePath := filepath.Join("/app/cp/", fileName)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	err = writerFunc(writer)
+	if err != nil {
+		writer.Write([]string{"err:", err.Error()})
+		return err
+	}
+	mu.Lock()
+	download[key] = fileName //确认文件已经完成
+	mu.Unlock()
+	ok = true
+	return nil
+}
+
+func removeKey(key string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(download, key)
 }
 
 // GetFilepath retrieves the file path associated with the given key.
@@ -4,10 +4,17 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"github.com/wg00001/wgo-sdk/pool"
 )
 
 //Provides a function that can be used for fast chunk write into csv.writer.
 
+// rowBuffers reuses the chunk slices between ChunkWrite calls.
+var rowBuffers = pool.NewObjects(func() [][]string { return nil }, func(rows *[][]string) {
+	clear((*rows)[:cap(*rows)]) // drop references to the written rows
+	*rows = (*rows)[:0]
+})
+
 // CsvRow defines an interface for objects that can be written to a CSV file.
 // Types that implement this interface must provide a ToStringSlice method,
 // which returns the data of the object as a slice of strings.
@@ -40,7 +47,15 @@ func ChunkWrite[T CsvRow](writer *csv.Writer, data []T, size ...int) (err error)
 	if len(size) != 0 {
 		pageSize = size[0]
 	}
-	cur := make([][]string, 0, pageSize+5)
+	buf := rowBuffers.Get()
+	if cap(*buf) < pageSize+5 {
+		*buf = make([][]string, 0, pageSize+5)
+	}
+	cur := *buf
+	defer func() {
+		*buf = cur
+		rowBuffers.Put(buf)
+	}()
 	for i := 0; i < len(data); i++ {
 		cur = append(cur, data[i].ToStringSlice())
 		if (i+1)%pageSize == 0 {
@@ -1,6 +1,7 @@
 package wgorm
 
 import (
+	"context"
 	"errors"
 	"github.com/wg00001/wgo-sdk/pool"
 	"gorm.io/driver/clickhouse"
@@ -24,12 +25,15 @@ type Option struct {
 }
 
 type Pool struct {
-	mu     sync.Mutex
-	conn   []*WGorm
-	option Option
+	mu       sync.Mutex
+	conn     []*WGorm              // 空闲连接
+	borrowed map[*gorm.DB]struct{} // 已借出的连接
+	closed   bool
+	option   Option
 }
 
 var _ pool.Pool[WGorm] = (*Pool)(nil)
+var _ pool.StatsProvider = (*Pool)(nil)
 var _ pool.Option = (*Option)(nil)
 
 func (p *Pool) Init(option pool.Option) error {
@@ -48,16 +52,119 @@ func (p *Pool) Init(option pool.Option) error {
 	return nil
 }
 
-func (p *Pool) Get() (WGorm, error) {
-	return WGorm{}, nil
+var ErrNilConn = errors.New("wgorm: put nil connection")
+
+// Get 优先取出空闲连接,没有时新建
+func (p *Pool) Get(ctx context.Context) (WGorm, error) {
+	if err := ctx.Err(); err != nil {
+		return WGorm{}, err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return WGorm{}, pool.ErrPoolClosed
+	}
+	if n := len(p.conn); n > 0 {
+		conn := p.conn[n-1]
+		p.conn = p.conn[:n-1]
+		p.borrow(conn.DB)
+		p.mu.Unlock()
+		return *conn, nil
+	}
+	p.mu.Unlock()
+	conn, err := p.Open()
+	if err != nil {
+		return conn, err
+	}
+	p.mu.Lock()
+	p.borrow(conn.DB)
+	p.mu.Unlock()
+	return conn, nil
+}
+
+func (p *Pool) borrow(db *gorm.DB) {
+	if p.borrowed == nil {
+		p.borrowed = make(map[*gorm.DB]struct{})
+	}
+	p.borrowed[db] = struct{}{}
+}
+
+// Put 归还连接; 池已关闭或空闲数已达 MaxIdleConnection 时关闭该连接
+func (p *Pool) Put(item WGorm) error {
+	if item.DB == nil {
+		return ErrNilConn
+	}
+	p.mu.Lock()
+	delete(p.borrowed, item.DB)
+	if !p.closed && (p.option.MaxIdleConnection <= 0 || len(p.conn) < p.option.MaxIdleConnection) {
+		p.conn = append(p.conn, &item)
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+	return closeConn(item.DB)
 }
 
+// CloseAll 关闭所有空闲连接; 借出的连接在 Put 时关闭
 func (p *Pool) CloseAll() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.conn
+	p.conn = nil
+	p.mu.Unlock()
+	for _, conn := range idle {
+		if conn != nil && conn.DB != nil {
+			_ = closeConn(conn.DB)
+		}
+	}
+}
 
+func closeConn(gdb *gorm.DB) error {
+	db, err := gdb.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
 }
 
+// Len 打开的连接数(空闲+借出)
 func (p *Pool) Len() int {
-	return 0
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conn) + len(p.borrowed)
+}
+
+// Stats 汇总所有空闲和借出连接底层 database/sql 连接池的统计
+func (p *Pool) Stats() pool.Stats {
+	p.mu.Lock()
+	dbs := make([]*gorm.DB, 0, len(p.conn)+len(p.borrowed))
+	for _, conn := range p.conn {
+		if conn != nil && conn.DB != nil {
+			dbs = append(dbs, conn.DB)
+		}
+	}
+	for db := range p.borrowed {
+		dbs = append(dbs, db)
+	}
+	p.mu.Unlock()
+	var stats pool.Stats
+	for _, gdb := range dbs {
+		db, err := gdb.DB()
+		if err != nil {
+			continue
+		}
+		s := db.Stats()
+		stats.MaxOpen += s.MaxOpenConnections
+		stats.Open += s.OpenConnections
+		stats.Idle += s.Idle
+		stats.InUse += s.InUse
+		stats.WaitCount += s.WaitCount
+		stats.WaitDuration += s.WaitDuration
+		stats.ClosedIdle += s.MaxIdleTimeClosed
+		stats.ClosedMaxIdle += s.MaxIdleClosed
+		stats.ClosedLifetime += s.MaxLifetimeClosed
+	}
+	return stats
 }
 
 func (p *Pool) Open() (WGorm, error) {
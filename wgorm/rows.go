@@ -0,0 +1,221 @@
+package wgorm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/wg00001/wgo-sdk/aggs"
+	"math"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Kind 规范化的目标类型
+type Kind int
+
+const (
+	KindAuto    Kind = iota // 按值的类型自动转换
+	KindInt                 // int64, 非整数时保留 decimal.Decimal
+	KindDecimal             // decimal.Decimal
+	KindTime                // time.Time
+	KindString              // string
+)
+
+// NormalizeRule 某个驱动的类型映射规则
+// 优先级: Columns > Types > 默认规则
+type NormalizeRule struct {
+	Columns map[string]Kind       // 按列名指定目标类型
+	Types   map[reflect.Type]Kind // 按驱动返回的Go类型指定目标类型
+	// ParseStrings 是否像 []byte 一样尝试将string解析成数字和时间,默认只解析 []byte
+	ParseStrings bool
+}
+
+var (
+	rulesMu     sync.RWMutex
+	driverRules = map[string]NormalizeRule{}
+
+	intPattern     = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	decimalPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)\.[0-9]+$`)
+	timePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}:\d{2}(\.\d+)?)?`)
+)
+
+// SetNormalizeRule 设置驱动(gorm.Dialector.Name(), 如 mysql/clickhouse/sqlite)的类型映射规则
+func SetNormalizeRule(driver string, rule NormalizeRule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	driverRules[driver] = rule
+}
+
+func getNormalizeRule(driver string) NormalizeRule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return driverRules[driver]
+}
+
+// RawRows 执行原生SQL,并将结果按当前驱动的规则规范化成 []aggs.Row
+func (q *WGorm) RawRows(query string, values ...interface{}) ([]aggs.Row, error) {
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	var raw []map[string]interface{}
+	if err := q.DB.Raw(query, values...).Scan(&raw).Error; err != nil {
+		return nil, err
+	}
+	return NormalizeRows(getNormalizeRule(q.Dialector.Name()), raw), nil
+}
+
+// FindRows 使用当前构建的查询条件查询,并将结果规范化成 []aggs.Row
+func (q *WGorm) FindRows() ([]aggs.Row, error) {
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	var raw []map[string]interface{}
+	if err := q.DB.Find(&raw).Error; err != nil {
+		return nil, err
+	}
+	return NormalizeRows(getNormalizeRule(q.Dialector.Name()), raw), nil
+}
+
+// NormalizeRows 将 Scan 到 map 的查询结果规范化,值只会是 int64, decimal.Decimal, time.Time, string 或 nil
+func NormalizeRows(rule NormalizeRule, raw []map[string]interface{}) []aggs.Row {
+	rows := make([]aggs.Row, 0, len(raw))
+	for _, m := range raw {
+		row := make(aggs.Row, len(m))
+		for column, value := range m {
+			row[column] = rule.Normalize(column, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Normalize 按规则规范化单个值
+func (r NormalizeRule) Normalize(column string, value interface{}) interface{} {
+	value = unwrap(value)
+	if value == nil {
+		return nil
+	}
+	kind, ok := r.Columns[column]
+	if !ok {
+		kind = r.Types[reflect.TypeOf(value)]
+	}
+	switch kind {
+	case KindInt:
+		if d, ok := aggs.ToDecimal(value); ok {
+			return decimalToCanonical(d)
+		}
+	case KindDecimal:
+		if d, ok := aggs.ToDecimal(value); ok {
+			return d
+		}
+	case KindTime:
+		if t, ok := aggs.ToTime(value); ok {
+			return t
+		}
+	case KindString:
+		return toString(value)
+	}
+	return r.auto(value)
+}
+
+// auto 默认规则: 整数 -> int64(溢出时为decimal), 浮点 -> decimal, []byte 按内容解析成数字/时间/字符串
+func (r NormalizeRule) auto(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64, decimal.Decimal, time.Time:
+		return v
+	case int, int8, int16, int32, uint, uint8, uint16, uint32:
+		d, _ := aggs.ToDecimal(v)
+		return d.IntPart()
+	case uint64:
+		if v > math.MaxInt64 {
+			return decimal.NewFromUint64(v)
+		}
+		return int64(v)
+	case float32, float64:
+		d, _ := aggs.ToDecimal(v)
+		return d
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case []byte:
+		return parseText(string(v))
+	case string:
+		if r.ParseStrings {
+			return parseText(v)
+		}
+		return v
+	}
+	return toString(value)
+}
+
+// parseText 只解析规范的数字(不含前导0,避免把编码类字符串转成数字)和日期时间
+func parseText(s string) interface{} {
+	switch {
+	case intPattern.MatchString(s):
+		if d, ok := aggs.ToDecimal(s); ok {
+			return decimalToCanonical(d)
+		}
+	case decimalPattern.MatchString(s):
+		if d, ok := aggs.ToDecimal(s); ok {
+			return d
+		}
+	case timePattern.MatchString(s):
+		if t, ok := aggs.ToTime(s); ok {
+			return t
+		}
+	}
+	return s
+}
+
+// decimalToCanonical 可以用int64表示的整数返回int64,否则返回decimal
+func decimalToCanonical(d decimal.Decimal) interface{} {
+	if d.IsInteger() && d.GreaterThanOrEqual(decimal.NewFromInt(math.MinInt64)) && d.LessThanOrEqual(decimal.NewFromInt(math.MaxInt64)) {
+		return d.IntPart()
+	}
+	return d
+}
+
+// unwrap 解开指针和 driver.Valuer(如 sql.NullString)
+func unwrap(value interface{}) interface{} {
+	for i := 0; i < 8 && value != nil; i++ {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil
+			}
+			value = rv.Elem().Interface()
+			continue
+		}
+		if _, ok := value.(decimal.Decimal); ok {
+			return value
+		}
+		valuer, ok := value.(driver.Valuer)
+		if !ok {
+			return value
+		}
+		v, err := valuer.Value()
+		if err != nil {
+			return value
+		}
+		value = v
+	}
+	return value
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case decimal.Decimal:
+		return v.String()
+	case time.Time:
+		return v.Format(time.DateTime)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -1,9 +1,117 @@
 package wgorm
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/wg00001/wgo-sdk/pool"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 	"testing"
+	"time"
 )
 
 func TestIfWhere(t *testing.T) {
 
 }
+
+func TestNormalizeRows(t *testing.T) {
+	amount := decimal.RequireFromString("12.50")
+	rows := NormalizeRows(NormalizeRule{Columns: map[string]Kind{"code": KindString}}, []map[string]interface{}{{
+		"mysql_int":   []byte("42"),
+		"mysql_dec":   []byte("3.14"),
+		"mysql_time":  []byte("2024-05-01 10:00:00"),
+		"zip":         []byte("0012"),
+		"code":        []byte("100"),
+		"ch_decimal":  &amount,
+		"ch_uint":     uint64(7),
+		"float":       1.5,
+		"null_string": sql.NullString{String: "x", Valid: true},
+		"null":        sql.NullInt64{},
+	}})
+	row := rows[0]
+	if row["mysql_int"] != int64(42) || row["ch_uint"] != int64(7) || row["zip"] != "0012" || row["code"] != "100" ||
+		row["null_string"] != "x" || row["null"] != nil {
+		t.Fatalf("unexpected row: %v", row)
+	}
+	if d, ok := row["mysql_dec"].(decimal.Decimal); !ok || d.String() != "3.14" {
+		t.Fatalf("unexpected decimal: %#v", row["mysql_dec"])
+	}
+	if d, ok := row["ch_decimal"].(decimal.Decimal); !ok || !d.Equal(amount) {
+		t.Fatalf("unexpected clickhouse decimal: %#v", row["ch_decimal"])
+	}
+	if _, ok := row["float"].(decimal.Decimal); !ok {
+		t.Fatalf("float should be decimal: %#v", row["float"])
+	}
+	if tm, ok := row["mysql_time"].(time.Time); !ok || tm.Hour() != 10 {
+		t.Fatalf("unexpected time: %#v", row["mysql_time"])
+	}
+}
+
+func TestRawRows(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewWGorm(db)
+	rows, err := q.RawRows("SELECT 1 AS id, 2.5 AS amount, 'a' AS name, NULL AS empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != int64(1) || rows[0]["name"] != "a" || rows[0]["empty"] != nil {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	if d, ok := rows[0]["amount"].(decimal.Decimal); !ok || d.String() != "2.5" {
+		t.Fatalf("unexpected amount: %#v", rows[0]["amount"])
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	var p Pool
+	if err := p.Init(Option{DSN: ":memory:", Driver: "sqlite", MaxIdleConnection: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 2 {
+		t.Fatalf("len = %d", p.Len())
+	}
+	if s := p.Stats(); s.Open != 2 || s.InUse != 0 {
+		t.Fatalf("stats: %+v", s)
+	}
+
+	conn, err := p.Get(context.Background())
+	if err != nil || conn.DB == nil || p.Len() != 2 {
+		t.Fatalf("get: %v, len = %d", err, p.Len())
+	}
+	if s := p.Stats(); s.Open != 2 {
+		t.Fatalf("borrowed connection missing from stats: %+v", s)
+	}
+	var one int
+	if err = conn.Raw("select 1").Scan(&one).Error; err != nil || one != 1 {
+		t.Fatalf("query: %v, %d", err, one)
+	}
+	extra, _ := p.Open()
+	if err = p.Put(conn); err != nil || p.Len() != 2 {
+		t.Fatalf("put: %v, len = %d", err, p.Len())
+	}
+	if err = p.Put(extra); err != nil || p.Len() != 2 {
+		t.Fatalf("put over MaxIdleConnection: %v, len = %d", err, p.Len())
+	}
+	if err = p.Put(WGorm{}); !errors.Is(err, ErrNilConn) {
+		t.Fatalf("put zero value: %v", err)
+	}
+
+	borrowed, _ := p.Get(context.Background())
+	idle, _ := p.conn[0].DB.DB()
+	p.CloseAll()
+	if err = idle.Ping(); err == nil || p.Len() != 1 {
+		t.Fatalf("idle connection should be closed: %v, len = %d", err, p.Len())
+	}
+	if _, err = p.Get(context.Background()); !errors.Is(err, pool.ErrPoolClosed) {
+		t.Fatalf("get after CloseAll: %v", err)
+	}
+	db, _ := borrowed.DB.DB()
+	if err = p.Put(borrowed); err != nil || db.Ping() == nil || p.Len() != 0 {
+		t.Fatalf("put after CloseAll should close the connection: %v, len = %d", err, p.Len())
+	}
+}